@@ -0,0 +1,198 @@
+package pocket
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"pocket-pick/config"
+	"pocket-pick/pkg/cache"
+)
+
+// metricCache stats of cache backend, published as pocket_cache
+var metricCache atomic.Pointer[cache.Instrumented]
+
+func init() {
+	expvar.Publish("pocket_cache", expvar.Func(func() any {
+		if stats := metricCache.Load(); stats != nil {
+			return stats.Stats()
+		}
+		return nil
+	}))
+}
+
+// CacheEntry metadata of cached value, values are not exposed as they contain user data
+type CacheEntry struct {
+	Key  string        `json:"key"`
+	Size int           `json:"size"`
+	TTL  time.Duration `json:"ttl"` // 0 if no expiration
+}
+
+// CacheFlushResult result of flush keys
+type CacheFlushResult struct {
+	Deleted int `json:"deleted"`
+	Kept    int `json:"kept"` // state keys kept without force, e.g. trash
+}
+
+// favoritesRequest articles of user changed by commands
+type favoritesRequest struct {
+	AccessToken string   `form:"access_token"`
+	ItemIDs     []string `form:"item_id"`
+}
+
+// requireAdminToken authenticate admin api with bearer token of admin_token
+func requireAdminToken(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(config.AdminToken())) != 1 {
+			return echo.NewHTTPError(http.StatusUnauthorized, "invalid admin token")
+		}
+
+		return next(c)
+	}
+}
+
+// cacheError convert cache error to http error
+func cacheError(err error) error {
+	if err == cache.ErrNotSupported {
+		return echo.NewHTTPError(http.StatusNotImplemented, "not supported by cache backend "+config.CacheBackend())
+	}
+	return err
+}
+
+// setupAdminRoute setup api to inspect cache and metrics
+// metrics expose command line and memory stats, so they are served only to admin
+func (s *pocketService) setupAdminRoute(g *echo.Group) {
+	g.GET("/debug/vars", echo.WrapHandler(expvar.Handler()))
+	g.GET("/cache/stats", s.handleGetCacheStats)
+	g.GET("/cache/keys", s.handleGetCacheKeys)
+	g.DELETE("/cache/keys", s.handleDeleteCacheKeys)
+	g.GET("/cache/entry", s.handleGetCacheEntry)
+	g.POST("/favorites/forget", s.handlePostFavoritesForget)
+	g.POST("/favorites/resync", s.handlePostFavoritesResync)
+}
+
+func (s *pocketService) handleGetCacheStats(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.cache.stats.Stats())
+}
+
+// list keys which start with prefix
+func (s *pocketService) handleGetCacheKeys(c echo.Context) error {
+	keys, err := s.cache.Keys(c.Request().Context(), c.QueryParam("prefix"))
+	if err != nil {
+		return cacheError(err)
+	}
+
+	if keys == nil {
+		keys = []string{}
+	}
+
+	return c.JSON(http.StatusOK, keys)
+}
+
+// flush keys which start with prefix
+// state keys which can not be rebuilt from pocket are kept unless force=true
+func (s *pocketService) handleDeleteCacheKeys(c echo.Context) error {
+	prefix := c.QueryParam("prefix")
+	if prefix == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "prefix required")
+	}
+
+	ctx := c.Request().Context()
+	keys, err := s.cache.Keys(ctx, prefix)
+	if err != nil {
+		return cacheError(err)
+	}
+
+	total := len(keys)
+	if force, _ := strconv.ParseBool(c.QueryParam("force")); !force {
+		keys = slices.DeleteFunc(keys, isStateKey)
+	}
+
+	if err := s.cache.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	// drop indexes, they are rebuilt from cache
+	s.indexes.Purge()
+
+	return c.JSON(http.StatusOK, &CacheFlushResult{Deleted: len(keys), Kept: total - len(keys)})
+}
+
+// isStateKey return true if key is trash, sync state or lock of user, which are lost if deleted
+func isStateKey(key string) bool {
+	return strings.HasSuffix(key, ":"+keyTrash) || strings.HasSuffix(key, ":"+keyTrashUsers) || strings.HasSuffix(key, ":"+keyFavoritesSync) || strings.Contains(key, ":lock/")
+}
+
+// inspect cached value of the key
+func (s *pocketService) handleGetCacheEntry(c echo.Context) error {
+	key := c.QueryParam("key")
+	if key == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "key required")
+	}
+
+	ctx := c.Request().Context()
+	value, err := s.cache.Get(ctx, key)
+	if err != nil {
+		if err == cache.ErrNotExists {
+			return echo.NewHTTPError(http.StatusNotFound, "key not exists: "+key)
+		}
+		return err
+	}
+
+	ttl, err := s.cache.TTL(ctx, key)
+	if err != nil && err != cache.ErrNotExists {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &CacheEntry{Key: key, Size: len(value), TTL: ttl})
+}
+
+// bindFavoritesRequest bind request of commands which changed articles of user
+func bindFavoritesRequest(c echo.Context) (*favoritesRequest, error) {
+	var req favoritesRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, err
+	}
+
+	if req.AccessToken == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "access_token required")
+	}
+
+	return &req, nil
+}
+
+// remove articles deleted by commands from cached favorites of user
+func (s *pocketService) handlePostFavoritesForget(c echo.Context) error {
+	req, err := bindFavoritesRequest(c)
+	if err != nil {
+		return err
+	}
+
+	if err := s.forgetArticles(c.Request().Context(), req.AccessToken, req.ItemIDs...); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// sync all favorites of user on next pick, after commands restored articles
+func (s *pocketService) handlePostFavoritesResync(c echo.Context) error {
+	req, err := bindFavoritesRequest(c)
+	if err != nil {
+		return err
+	}
+
+	if err := s.resyncFavorites(c.Request().Context(), req.AccessToken); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
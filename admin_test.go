@@ -0,0 +1,183 @@
+package pocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"github.com/whitekid/getpocket"
+	"golang.org/x/exp/maps"
+
+	"pocket-pick/pkg/cache"
+)
+
+func TestAdminCache(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	defer viper.Set("admin_token", viper.Get("admin_token"))
+	viper.Set("admin_token", "admin-secret")
+
+	svc, err := New(ctx)
+	require.NoError(t, err)
+	s := svc.(*pocketService)
+
+	ts := httptest.NewServer(s.setupRoute())
+	defer ts.Close()
+
+	require.NoError(t, s.cache.Set(ctx, "test:1", []byte("value1"), cache.WithExpire(time.Hour)))
+	require.NoError(t, s.cache.Set(ctx, "test:2", []byte("value2")))
+	require.NoError(t, s.cache.Set(ctx, "other:1", []byte("value")))
+
+	do := func(t *testing.T, method string, path string, query url.Values, token string, v any) int {
+		req, err := http.NewRequestWithContext(ctx, method, ts.URL+path+"?"+query.Encode(), nil)
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		if v != nil && resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	type args struct {
+		method string
+		path   string
+		query  url.Values
+		token  string
+	}
+	tests := [...]struct {
+		name       string
+		args       args
+		wantStatus int
+	}{
+		{"no token", args{http.MethodGet, "/admin/cache/stats", nil, ""}, http.StatusUnauthorized},
+		{"invalid token", args{http.MethodGet, "/admin/cache/stats", nil, "invalid"}, http.StatusUnauthorized},
+		{"stats", args{http.MethodGet, "/admin/cache/stats", nil, "admin-secret"}, http.StatusOK},
+		{"metrics without token", args{http.MethodGet, "/admin/debug/vars", nil, ""}, http.StatusUnauthorized},
+		{"metrics", args{http.MethodGet, "/admin/debug/vars", nil, "admin-secret"}, http.StatusOK},
+		{"keys", args{http.MethodGet, "/admin/cache/keys", url.Values{"prefix": {"test:"}}, "admin-secret"}, http.StatusOK},
+		{"entry", args{http.MethodGet, "/admin/cache/entry", url.Values{"key": {"test:1"}}, "admin-secret"}, http.StatusOK},
+		{"entry not exists", args{http.MethodGet, "/admin/cache/entry", url.Values{"key": {"test:3"}}, "admin-secret"}, http.StatusNotFound},
+		{"flush without prefix", args{http.MethodDelete, "/admin/cache/keys", nil, "admin-secret"}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.wantStatus, do(t, tt.args.method, tt.args.path, tt.args.query, tt.args.token, nil))
+		})
+	}
+
+	var keys []string
+	require.Equal(t, http.StatusOK, do(t, http.MethodGet, "/admin/cache/keys", url.Values{"prefix": {"test:"}}, "admin-secret", &keys))
+	require.ElementsMatch(t, []string{"test:1", "test:2"}, keys)
+
+	var entry CacheEntry
+	require.Equal(t, http.StatusOK, do(t, http.MethodGet, "/admin/cache/entry", url.Values{"key": {"test:1"}}, "admin-secret", &entry))
+	require.Equal(t, len("value1"), entry.Size)
+	require.Greater(t, entry.TTL, time.Minute)
+
+	var result CacheFlushResult
+	require.Equal(t, http.StatusOK, do(t, http.MethodDelete, "/admin/cache/keys", url.Values{"prefix": {"test:"}}, "admin-secret", &result))
+	require.Equal(t, 2, result.Deleted)
+	require.True(t, s.cache.Has(ctx, "other:1"), "keys of other prefix should be kept")
+
+	trash := s.keys.Key(ctx, "token", keyTrash)
+	state := s.keys.Key(ctx, "token", keyFavoritesSync)
+	favorites := s.keys.Key(ctx, "token", keyFavorites)
+	for _, key := range []string{trash, state, favorites} {
+		require.NoError(t, s.cache.Set(ctx, key, []byte("value")))
+	}
+	require.Equal(t, http.StatusOK, do(t, http.MethodDelete, "/admin/cache/keys", url.Values{"prefix": {s.keys.Prefix("token")}}, "admin-secret", &result))
+	require.Equal(t, CacheFlushResult{Deleted: 1, Kept: 2}, result)
+	require.False(t, s.cache.Has(ctx, favorites))
+	require.True(t, s.cache.Has(ctx, trash), "trash should be kept without force")
+	require.True(t, s.cache.Has(ctx, state), "sync state should be kept without force")
+
+	require.Equal(t, http.StatusOK, do(t, http.MethodDelete, "/admin/cache/keys", url.Values{"prefix": {s.keys.Prefix("token")}, "force": {"true"}}, "admin-secret", &result))
+	require.Equal(t, CacheFlushResult{Deleted: 2}, result)
+	require.False(t, s.cache.Has(ctx, trash))
+
+	var stats cache.Stats
+	require.Equal(t, http.StatusOK, do(t, http.MethodGet, "/admin/cache/stats", nil, "admin-secret", &stats))
+	require.NotZero(t, stats.Hits)
+	require.NotZero(t, stats.BytesWritten)
+	require.Contains(t, stats.Backend, "entries")
+}
+
+func TestAdminFavorites(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	defer viper.Set("admin_token", viper.Get("admin_token"))
+	viper.Set("admin_token", "admin-secret")
+
+	svc, err := New(ctx)
+	require.NoError(t, err)
+	s := svc.(*pocketService)
+
+	ts := httptest.NewServer(s.setupRoute())
+	defer ts.Close()
+
+	key := s.keys.Key(ctx, "token", keyFavorites)
+	require.NoError(t, s.favoritesCache.Set(ctx, key, &favoritesList{Version: "v1", Articles: map[string]*getpocket.Article{
+		"1": {ItemID: "1", Favorite: "1"},
+		"2": {ItemID: "2", Favorite: "1"},
+	}}, cache.WithExpire(time.Hour)))
+
+	post := func(t *testing.T, path string, form url.Values) int {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+path, strings.NewReader(form.Encode()))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer admin-secret")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	require.Equal(t, http.StatusBadRequest, post(t, "/admin/favorites/forget", url.Values{"item_id": {"1"}}), "access token required")
+
+	require.Equal(t, http.StatusNoContent, post(t, "/admin/favorites/forget", url.Values{"access_token": {"token"}, "item_id": {"1"}}))
+	list, err := s.favoritesCache.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []string{"2"}, maps.Keys(list.Articles))
+
+	require.Equal(t, http.StatusNoContent, post(t, "/admin/favorites/resync", url.Values{"access_token": {"token"}}))
+	require.False(t, s.cache.Has(ctx, key), "cached favorites should be dropped")
+}
+
+func TestAdminDisabled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ts := newTestServer(t, ctx)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/admin/cache/stats", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer ")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusNotFound, resp.StatusCode, "admin api should be disabled without admin_token")
+
+	resp, err = http.Get(ts.URL + "/debug/vars")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode, "metrics should not be exposed without admin_token")
+}
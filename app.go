@@ -1,22 +1,28 @@
 package pocket
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
 	"github.com/whitekid/echox"
 	"github.com/whitekid/getpocket"
-	"github.com/whitekid/goxp/fx"
 	"github.com/whitekid/goxp/log"
 	"github.com/whitekid/goxp/service"
+	"golang.org/x/exp/maps"
 
 	"pocket-pick/config"
 	"pocket-pick/pkg/cache"
@@ -25,31 +31,351 @@ import (
 const (
 	keyRequestToken = "REQUEST_TOKEN"
 	keyAccessToken  = "ACCESS_TOKEN"
+
+	trashFlushInterval = time.Minute
+	favoritesLockTTL   = time.Second * 30 // lease of favorites sync, renewed while syncing
+
+	indexCacheSize = 1024             // users whose index is kept in memory
+	indexTTL       = time.Minute * 30 // rebuild index of inactive users from cache
 )
 
+// cache keys of user
+const (
+	keyFavorites        = "favorites"
+	keyFavoritesSync    = "favorites/sync"
+	keyFavoritesVersion = "favorites/version"
+	keyTrash            = "trash"
+	keyWarmUp           = "warmup" // lock name of warm up, not a cache key
+	keyTrashUsers       = "trash/users" // index of users with trash, not a key of user
+)
+
+// cacheSchemaVersion version of cached data, increase it to drop cached data of previous version
+// keys of version 1 differ only by hash tag, so they are moved to keys of current version
+const cacheSchemaVersion = 2
+
+// legacyUserKey return cache key for user data of previous versions, which has access token in plain text
+func legacyUserKey(accessToken string, name string) string {
+	return fmt.Sprintf("%s/%s", accessToken, name)
+}
+
 // New return pocket-pick service object
 // implements service interface
-func New(ctx context.Context) service.Interface {
+func New(ctx context.Context) (service.Interface, error) {
 	rootURL := config.RootURL()
 	if rootURL == "" {
-		panic("ROOT_URL required")
+		return nil, errors.New("ROOT_URL required")
 	}
 
+	return newPocketService(ctx, rootURL)
+}
+
+// newPocketService return service with cache of configured backend
+func newPocketService(ctx context.Context, rootURL string) (*pocketService, error) {
+	serializer, err := cache.ParseSerializer(config.CacheSerializer())
+	if err != nil {
+		return nil, err
+	}
+
+	keys := cache.NewKeyBuilder("pocket-pick", cacheSchemaVersion, config.SecretKey())
+
+	c, err := newCache(ctx, keys.Scope)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cache backend %s", config.CacheBackend())
+	}
+	keys.WithPrevious(c, 1).WithLegacy(c, legacyUserKey)
+	metricCache.Store(c.stats)
+
 	return &pocketService{
-		cache:   cache.NewBigCache(ctx),
-		rootURL: rootURL,
+		rootURL:        rootURL,
+		cache:          c,
+		locker:         c.locker,
+		keys:           keys,
+		indexes:        cache.NewLRU[string, *articleIndex](indexCacheSize, indexTTL),
+		favoritesCache: cache.NewTyped[*favoritesList](c, serializer),
+		versionCache:   cache.NewTyped[string](c, serializer),
+		syncCache:      cache.NewTyped[*favoritesSync](c, serializer),
+		trash: NewTrash(newTrashStore(c, keys, serializer)).WithLocker(c.locker, func(accessToken string) string {
+			return userLockName(keys, accessToken, keyTrash)
+		}),
+	}, nil
+}
+
+// newTrashStore return store of trash which keeps entries until they are flushed
+// trash is kept in the cache if it does not evict values, e.g. redis, and values are encrypted as access tokens are kept with it
+// otherwise it is kept in trash_file
+func newTrashStore(c *serviceCache, keys *cache.KeyBuilder, serializer cache.Serializer) TrashStore {
+	if c.durable == nil {
+		return NewFileTrashStore(config.TrashFile())
+	}
+
+	if !config.CacheEncrypt() {
+		log.Warnf("trash is kept in trash_file, enable cache_encrypt to keep it in %s", config.CacheBackend())
+		return NewFileTrashStore(config.TrashFile())
+	}
+
+	return newCacheTrashStore(c.durable, serializer, keys, c.locker)
+}
+
+// OpenTrash return trash in the store which the server uses for configured cache backend, so commands and the server share it
+// flush of the trash is locked with the server if locks are shared, e.g. with redis
+func OpenTrash(ctx context.Context) (*Trash, error) {
+	if !durableCacheBackend(config.CacheBackend()) || !config.CacheEncrypt() {
+		return NewTrash(NewFileTrashStore(config.TrashFile())), nil
+	}
+
+	s, err := newPocketService(ctx, config.RootURL())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.trash, nil
+}
+
+// ErrCacheNotShared cache backend is kept in the server process, commands can not update it
+var ErrCacheNotShared = errors.New("cache backend is not shared with the server")
+
+// ArticleCache articles of users cached by the service
+// commands use it to update cached articles after they changed articles; the cache backend should be shared with the service, e.g. redis
+type ArticleCache struct {
+	s *pocketService
+}
+
+// NewArticleCache return articles cached in configured backend
+// return ErrCacheNotShared if the backend is kept in the server process, e.g. bigcache and disk; use admin api of the server for them
+func NewArticleCache(ctx context.Context) (*ArticleCache, error) {
+	if !sharedCacheBackend(config.CacheBackend()) {
+		return nil, ErrCacheNotShared
+	}
+
+	s, err := newPocketService(ctx, config.RootURL())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ArticleCache{s: s}, nil
+}
+
+// Forget remove articles from cached articles of user
+func (c *ArticleCache) Forget(ctx context.Context, accessToken string, itemIDs ...string) error {
+	return c.s.forgetArticles(ctx, accessToken, itemIDs...)
+}
+
+// Resync drop cached articles of user and sync all articles with pocket on next pick
+func (c *ArticleCache) Resync(ctx context.Context, accessToken string) error {
+	return c.s.resyncFavorites(ctx, accessToken)
+}
+
+// TryLock acquire lock of job of user shared with the service, return cache.ErrLocked if the job is running elsewhere
+func (c *ArticleCache) TryLock(ctx context.Context, accessToken string, job string, ttl time.Duration) (*cache.Lock, error) {
+	return c.s.locker.TryLock(ctx, c.s.lockName(accessToken, job), ttl)
+}
+
+// sharedCacheBackend return true if the backend is shared by server replicas and commands
+func sharedCacheBackend(backend string) bool {
+	return slices.Contains([]string{"redis", "tiered", "memcache"}, backend)
+}
+
+// durableCacheBackend return true if the backend does not evict values, so trash is kept in it; see newCacheBackend
+func durableCacheBackend(backend string) bool {
+	return slices.Contains([]string{"redis", "tiered"}, backend)
+}
+
+// serviceCache cache of configured backend with its stats and locker
+type serviceCache struct {
+	cache.Interface
+	durable cache.Interface     // store of backend which does not evict values, nil if backend may evict them
+	stats   *cache.Instrumented // stats of backend
+	locker  cache.Locker        // locks shared with other replicas if backend is shared
+}
+
+// newCache return cache of configured backend, values are compressed with configured codec
+// values are encrypted with keys derived for scope of the key if encryption enabled
+func newCache(ctx context.Context, scope func(key string) string) (*serviceCache, error) {
+	format, err := cache.ParseFormat(config.CacheCodec())
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []cache.CodecOption
+	if path := config.CacheZstdDict(); path != "" {
+		dict, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "zstd dictionary")
+		}
+		opts = append(opts, cache.WithZstdDictionary(dict))
+	}
+
+	var secrets map[byte]string
+	var current byte
+	if config.CacheEncrypt() {
+		if secrets, current, err = parseEncryptionKeys(config.CacheEncryptionKeys()); err != nil {
+			return nil, err
+		}
+	}
+
+	// encrypt and compress values stored to c
+	wrap := func(c cache.Interface) (cache.Interface, error) {
+		var err error
+		if secrets != nil {
+			if c, err = cache.NewEncrypted(c, secrets, current, scope); err != nil {
+				return nil, err
+			}
+		}
+
+		return cache.NewCodec(c, format, config.CacheCompressThreshold(), opts...)
+	}
+
+	backend, durable, locker, err := newCacheBackend(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := cache.NewInstrumented(backend)
+	c, err := wrap(stats)
+	if err != nil {
+		return nil, err
+	}
+
+	if durable != nil {
+		if durable, err = wrap(durable); err != nil {
+			return nil, err
+		}
+	}
+
+	return &serviceCache{Interface: stats.ObserveLoads(c), durable: durable, stats: stats, locker: locker}, nil
+}
+
+// parseEncryptionKeys parse comma separated id:secret, first one is current key
+// secret key is used as key id 1 if empty
+func parseEncryptionKeys(keys string) (map[byte]string, byte, error) {
+	if keys == "" {
+		if config.SecretKey() == "" {
+			return nil, 0, errors.New("secret or cache_encryption_keys required")
+		}
+		return map[byte]string{1: config.SecretKey()}, 1, nil
+	}
+
+	secrets := make(map[byte]string)
+	var current byte
+	for i, key := range strings.Split(keys, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(key), ":")
+		keyID, err := strconv.ParseUint(id, 10, 8)
+		if !ok || err != nil || secret == "" {
+			return nil, 0, errors.Errorf("invalid encryption key, id:secret required: %s", id)
+		}
+
+		if i == 0 {
+			current = byte(keyID)
+		}
+		secrets[byte(keyID)] = secret
+	}
+
+	return secrets, current, nil
+}
+
+// newCacheBackend return cache of configured backend, its store which does not evict values and locker on the same store
+// store is nil if backend may evict values, e.g. bigcache, memcache and disk
+func newCacheBackend(ctx context.Context) (cache.Interface, cache.Interface, cache.Locker, error) {
+	switch backend := config.CacheBackend(); backend {
+	case "bigcache":
+		c, err := cache.NewBigCacheWithConfig(ctx, config.CacheShards(), config.CacheMaxSize())
+		return c, nil, cache.NewLocalLocker(), err
+
+	case "redis", "tiered":
+		r, err := newRedisClient(ctx)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if backend == "redis" {
+			return cache.NewRedis(r), cache.NewRedis(r), cache.NewRedisLocker(r), nil
+		}
+		c, err := cache.NewTiered(ctx, r, config.CacheL1TTL())
+		return c, cache.NewRedis(r), cache.NewRedisLocker(r), err
+
+	case "memcache":
+		if config.MemcacheServers() == "" {
+			return nil, nil, nil, errors.New("memcache_servers required")
+		}
+
+		servers := strings.Split(config.MemcacheServers(), ",")
+		for i := range servers {
+			servers[i] = strings.TrimSpace(servers[i])
+		}
+
+		client := memcache.New(servers...)
+		if err := client.Ping(); err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "memcache connect failed: %s", servers)
+		}
+
+		return cache.NewMemcache(client), nil, cache.NewMemcacheLocker(client), nil
+
+	case "disk":
+		path := config.CachePath()
+		if path == "" {
+			return nil, nil, nil, errors.New("cache_path required")
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, nil, nil, err
+		}
+
+		c, err := cache.NewDisk(ctx, path, int64(config.CacheMaxSize())<<20)
+		return c, nil, cache.NewLocalLocker(), err
+
+	case "none":
+		return cache.NewNop(), nil, cache.NewLocalLocker(), nil
+
+	default:
+		return nil, nil, nil, errors.Errorf("unknown cache backend: %s", backend)
 	}
 }
 
+// newRedisClient return redis client of redis_url, connection is checked with ping
+// standalone, sentinel and cluster are selected by scheme of the url
+func newRedisClient(ctx context.Context) (redis.UniversalClient, error) {
+	if config.RedisURL() == "" {
+		return nil, errors.New("redis_url required")
+	}
+
+	r, err := cache.NewRedisClient(config.RedisURL())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid redis_url")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
+	defer cancel()
+
+	if err := r.Ping(ctx).Err(); err != nil {
+		r.Close()
+		u, _ := url.Parse(config.RedisURL())
+		return nil, errors.Wrapf(err, "redis connect failed: %s", u.Redacted())
+	}
+
+	return r, nil
+}
+
 type pocketService struct {
-	rootURL string
-	cache   cache.Interface // for api cache
+	rootURL        string
+	cache          *serviceCache                     // cache of all user data
+	locker         cache.Locker                      // guard background work running on many replicas
+	keys           *cache.KeyBuilder                 // cache keys of user data
+	favoritesCache *cache.Typed[*favoritesList]      // favorite articles of user
+	versionCache   *cache.Typed[string]              // version of cached favorite articles
+	syncCache      *cache.Typed[*favoritesSync]      // favorite articles synced with pocket
+	indexes        *cache.LRU[string, *articleIndex] // access token -> index of favorites
+	trash          *Trash
 }
 
 // Serve serve the main service
 func (s *pocketService) Serve(ctx context.Context) error {
 	e := s.setupRoute()
 
+	if s.trash.Enabled() {
+		go s.flushTrash(ctx)
+	}
+	go s.probePocket(ctx)
+
 	go func() {
 		<-ctx.Done()
 		if err := e.Shutdown(context.Background()); err != nil {
@@ -84,6 +410,7 @@ func (f *ContextFactory) NewContext(c echo.Context) echo.Context {
 func (s *pocketService) setupRoute() *echox.Echo {
 	e := echox.New()
 	e.Use(echox.CustomContext(&ContextFactory{}))
+	e.Use(degradedMiddleware)
 	e.Use(session.Middleware(sessions.NewCookieStore([]byte("secret"))),
 		func(next echo.HandlerFunc) echo.HandlerFunc {
 			return func(c echo.Context) error {
@@ -105,8 +432,19 @@ func (s *pocketService) setupRoute() *echox.Echo {
 
 	e.GET("/", s.handleGetIndex)
 	e.GET("/auth", s.handleGetAuth)
-	e.GET("/article/:item_id", s.handleGetArticle) // TODO 원래는 DELETE로 해야하는데, 귀찮아서..
+	if config.LegacyDelete() {
+		e.GET("/article/:item_id", s.handleGetArticle) // deprecated: use DELETE /api/v1/articles/:item_id
+	}
 	e.GET("/sessions", s.handleGetSession)
+	e.GET("/settings/bookmarklet", s.handleGetSettingsBookmarklet)
+	s.setupBookmarkletRoute(e.Group("/bookmarklet", bookmarkletCSRF()))
+	e.GET("/api/v1/library/status", s.handleGetLibraryStatus)
+
+	s.setupArticleRoute(e.Group("/api/v1/articles"))
+	s.setupTrashRoute(e.Group("/api/v1/trash"))
+	if config.AdminToken() != "" {
+		s.setupAdminRoute(e.Group("/admin", requireAdminToken))
+	}
 
 	return e
 }
@@ -140,8 +478,12 @@ func (s *pocketService) handleGetIndex(c echo.Context) error {
 
 	// if not token, try to authorize
 	if _, exists := sess.Values[keyRequestToken]; !exists {
-		requestToken, authorizedURL, err := getpocket.New(config.ConsumerKey(), "").
-			AuthorizedURL(ctx, fmt.Sprintf("%s/auth", s.rootURL))
+		var authorizedURL string
+		requestToken, err := callPocket(ctx, func(ctx context.Context) (requestToken string, err error) {
+			requestToken, authorizedURL, err = getpocket.New(config.ConsumerKey(), "").
+				AuthorizedURL(ctx, fmt.Sprintf("%s/auth", s.rootURL))
+			return
+		})
 		if err != nil {
 			return errors.Wrapf(err, "authorize failed")
 		}
@@ -162,46 +504,168 @@ func (s *pocketService) handleGetIndex(c echo.Context) error {
 	accessToken := sess.Values[keyAccessToken].(string)
 	log.Debugf("accessToken acquired, get random favorite pick: %s", accessToken)
 
-	key := fmt.Sprintf("%s/favorites", accessToken)
-	api := getpocket.New(config.ConsumerKey(), accessToken)
+	// favorites are prefetched after login, wait on preparing page instead of blocking the request
+	// errors of the check are ignored, favorites are loaded by this request then
+	if ready, err := s.libraryReady(ctx, accessToken); err == nil && !ready {
+		return renderPreparing(c, c.Request().RequestURI)
+	}
 
-	data, err := s.cache.Get(ctx, key)
-	var articleList map[string]*getpocket.Article
+	idx, degraded, err := s.favoritesIndex(ctx, accessToken)
 	if err != nil {
-		if err != cache.ErrNotExists {
-			return err
+		return err
+	}
+
+	if degraded {
+		metricDegraded.Add(1)
+		c.Response().Header().Set(headerDegraded, "true")
+	}
+
+	log.Debugf("you have %d articles", len(idx.articles))
+
+	// random pick from articles
+	article := idx.pick(c.QueryParam("tag"), c.QueryParam("domain"))
+	if article == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "no articles matched")
+	}
+	log.Debugf("article: %+v", article)
+
+	url := fmt.Sprintf("https://getpocket.com/read/%s", article.ItemID)
+
+	return c.Redirect(http.StatusFound, url)
+}
+
+// favoritesList favorite articles of user with version, version changes whenever articles are loaded from pocket
+type favoritesList struct {
+	Version  string                        `json:"version"`
+	Fence    int64                         `json:"fence,omitempty"` // fencing token of favorites lock it was written under
+	Articles map[string]*getpocket.Article `json:"articles"`
+}
+
+// loadedAfter return true if articles are loaded from pocket after t
+func (l *favoritesList) loadedAfter(t time.Time) bool {
+	loadedAt, err := strconv.ParseInt(l.Version, 36, 64)
+	return err == nil && loadedAt > t.UnixNano()
+}
+
+// dictionarySampleSize articles per sample of zstd dictionary
+const dictionarySampleSize = 20
+
+// DictionarySamples return articles serialized as cached favorites, to train zstd dictionary of cache_zstd_dict
+func DictionarySamples(articles map[string]*getpocket.Article) ([][]byte, error) {
+	serializer, err := cache.ParseSerializer(config.CacheSerializer())
+	if err != nil {
+		return nil, err
+	}
+
+	itemIDs := maps.Keys(articles)
+	slices.Sort(itemIDs)
+
+	var samples [][]byte
+	for start := 0; start < len(itemIDs); start += dictionarySampleSize {
+		chunk := make(map[string]*getpocket.Article, dictionarySampleSize)
+		for _, itemID := range itemIDs[start:min(start+dictionarySampleSize, len(itemIDs))] {
+			chunk[itemID] = articles[itemID]
 		}
-		var err error
-		articleList, err = api.Articles().Get().Favorite(getpocket.Favorited).Do(ctx)
+
+		data, err := serializer.Marshal(&favoritesList{Version: strconv.FormatInt(time.Now().UnixNano(), 36), Articles: chunk})
 		if err != nil {
-			return errors.Wrap(err, "get favorite artcles failed")
+			return nil, err
 		}
+		samples = append(samples, data)
+	}
+
+	return samples, nil
+}
 
-		// write to cache
-		buf, err := json.Marshal(articleList)
+// favoritesIndex return index of favorite articles of user
+// index is kept in memory until version of cached articles changed
+func (s *pocketService) favoritesIndex(ctx context.Context, accessToken string) (idx *articleIndex, degraded bool, err error) {
+	version, err := s.versionCache.Get(ctx, s.keys.Key(ctx, accessToken, keyFavoritesVersion))
+	if err == nil {
+		if idx, ok := s.indexes.Get(accessToken); ok && idx.version == version {
+			return idx, false, nil
+		}
+	}
+
+	list, degraded, err := s.favorites(ctx, accessToken)
+	if err != nil {
+		return nil, false, err
+	}
+
+	idx = newArticleIndex(list.Version, list.Articles)
+	if !degraded {
+		s.indexes.Add(accessToken, idx)
+	}
+
+	return idx, degraded, nil
+}
+
+// favorites return favorite articles of user, fetch from pocket if not cached
+// if pocket is not available, return last known articles as degraded
+func (s *pocketService) favorites(ctx context.Context, accessToken string) (list *favoritesList, degraded bool, err error) {
+	key := s.keys.Key(ctx, accessToken, keyFavorites)
+	list, err = s.favoritesCache.GetOrLoad(ctx, key, func(ctx context.Context) (*favoritesList, error) {
+		// only one replica syncs with pocket, others wait and take its result
+		started := time.Now()
+		lock, err := s.locker.Lock(ctx, s.lockName(accessToken, keyFavorites), favoritesLockTTL)
 		if err != nil {
-			return errors.Wrap(err, "json encode failed")
+			return nil, err
 		}
-		s.cache.Set(ctx, key, buf, cache.WithExpire(config.CacheEvictionTimeout()))
-	} else {
-		log.Debug("load articles from cache")
+		defer lock.Unlock(context.WithoutCancel(ctx))
 
-		articleList = make(map[string]*getpocket.Article)
-		buf := bytes.NewBuffer(data)
-		if err := json.NewDecoder(buf).Decode(&articleList); err != nil {
-			return errors.Wrap(err, "json decode failed")
+		if cached, err := s.favoritesCache.Get(ctx, key); err == nil && cached.loadedAfter(started) {
+			return cached, nil
 		}
-	}
 
-	log.Debugf("you have %d articles", len(articleList))
+		ctx, cancel := lock.KeepAlive(ctx)
+		defer cancel()
 
-	// random pick from articles
-	_, article := fx.SampleMap(articleList)
-	log.Debugf("article: %+v", article)
+		log.Debug("load articles from pocket")
 
-	url := fmt.Sprintf("https://getpocket.com/read/%s", article.ItemID)
+		articles, err := s.syncFavorites(ctx, accessToken, lock)
+		if err != nil {
+			// lease expired while syncing and others synced under newer lock, take their articles
+			if err == cache.ErrLockLost {
+				if cached, err := s.favoritesCache.Get(ctx, key); err == nil {
+					return cached, nil
+				}
+			}
+			return nil, err
+		}
 
-	return c.Redirect(http.StatusFound, url)
+		// version expires with soft expire, so stale articles are refreshed by favorites
+		loaded := &favoritesList{Version: strconv.FormatInt(time.Now().UnixNano(), 36), Fence: lock.Token(), Articles: articles}
+		versionExpire := config.CacheEvictionTimeout()
+		if versionExpire == 0 {
+			versionExpire = config.CacheMaxAge()
+		}
+		if err := s.versionCache.Set(ctx, s.keys.Key(ctx, accessToken, keyFavoritesVersion), loaded.Version, cache.WithExpire(versionExpire)); err != nil {
+			return nil, err
+		}
+
+		return loaded, nil
+	}, cache.WithExpire(config.CacheMaxAge()), cache.WithSoftExpire(config.CacheEvictionTimeout()))
+	if err != nil {
+		state, syncErr := s.loadFavoritesSync(ctx, accessToken)
+		if syncErr != nil {
+			return nil, false, err
+		}
+
+		log.Warnf("pocket not available, serve last known favorites: %s", err)
+		return &favoritesList{Articles: state.Articles}, true, nil
+	}
+
+	return list, false, nil
+}
+
+// cachedFavorites return favorite articles from cache, return cache.ErrNotExists if not cached
+func (s *pocketService) cachedFavorites(ctx context.Context, accessToken string) (map[string]*getpocket.Article, error) {
+	list, err := s.favoritesCache.Get(ctx, s.keys.Key(ctx, accessToken, keyFavorites))
+	if err != nil {
+		return nil, err
+	}
+
+	return list.Articles, nil
 }
 
 func (s *pocketService) handleGetAuth(c echo.Context) (err error) {
@@ -213,7 +677,10 @@ func (s *pocketService) handleGetAuth(c echo.Context) (err error) {
 
 	requestToken := sess.Values[keyRequestToken].(string)
 	if _, exists := sess.Values[keyAccessToken]; !exists {
-		accessToken, _, err := getpocket.New(config.ConsumerKey(), "").NewAccessToken(c.Request().Context(), requestToken)
+		accessToken, err := callPocket(c.Request().Context(), func(ctx context.Context) (string, error) {
+			accessToken, _, err := getpocket.New(config.ConsumerKey(), "").NewAccessToken(ctx, requestToken)
+			return accessToken, err
+		})
 		if err != nil {
 			log.Errorf("fail to get access token: %s", err)
 			return err
@@ -229,6 +696,13 @@ func (s *pocketService) handleGetAuth(c echo.Context) (err error) {
 		log.Debugf("get accessToken %s", accessToken)
 		sess.Values[keyAccessToken] = accessToken
 		sess.Save(c.Request(), c.Response())
+
+		// prefetch favorites, large libraries are prepared while preparing page polls
+		select {
+		case <-s.warmUp(c.Request().Context(), accessToken):
+		case <-time.After(warmUpWait):
+			return renderPreparing(c, s.rootURL)
+		}
 	}
 
 	log.Debug("redirect to root to read a item")
@@ -261,10 +735,51 @@ func (s *pocketService) handleGetArticle(c echo.Context) error {
 		return c.Redirect(http.StatusFound, s.rootURL)
 	}
 
-	if _, err := getpocket.New(config.ConsumerKey(), accessToken).Modify().Delete(itemID).Do(c.Request().Context()); err != nil {
+	entries, err := s.deleteArticles(c.Request().Context(), accessToken, itemID)
+	if err != nil {
 		log.Errorf("failed: %s", err)
-		return err
+		return serviceError(err)
+	}
+
+	if len(entries) > 0 {
+		return renderUndoBanner(c, entries[0])
 	}
 
 	return nil
 }
+
+// flushTrash delete articles in trash whose grace period passed
+// users are read from the trash store, so trash queued before restart or by other replicas is flushed too
+func (s *pocketService) flushTrash(ctx context.Context) {
+	ticker := time.NewTicker(trashFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		users, err := s.trash.Users(ctx)
+		if err != nil {
+			log.Errorf("list trash users failed: %s", err)
+			continue
+		}
+
+		for _, accessToken := range users {
+			if _, err := s.trash.Flush(ctx, accessToken); err != nil && err != cache.ErrLocked {
+				log.Errorf("flush trash failed: %s", err)
+			}
+		}
+	}
+}
+
+// lockName return name of lock for job of user
+func (s *pocketService) lockName(accessToken string, job string) string {
+	return userLockName(s.keys, accessToken, job)
+}
+
+func userLockName(keys *cache.KeyBuilder, accessToken string, job string) string {
+	return keys.Prefix(accessToken) + "lock/" + job
+}
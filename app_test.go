@@ -4,17 +4,26 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 	"github.com/whitekid/goxp/request"
+	"golang.org/x/exp/maps"
+
+	"pocket-pick/pkg/cache"
 )
 
-func newTestServer(ctx context.Context) *httptest.Server {
-	s := New(ctx).(*pocketService)
+func newTestServer(t *testing.T, ctx context.Context) *httptest.Server {
+	svc, err := New(ctx)
+	require.NoError(t, err)
+
+	s := svc.(*pocketService)
 	e := s.setupRoute()
 
 	ts := httptest.NewServer(e)
@@ -29,7 +38,7 @@ func TestSession(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	ts := newTestServer(ctx)
+	ts := newTestServer(t, ctx)
 
 	sess := request.NewSession(nil)
 
@@ -49,7 +58,7 @@ func TestIndex(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	ts := newTestServer(ctx)
+	ts := newTestServer(t, ctx)
 
 	// check if redirect to authorize url
 	resp, err := request.Get("%s", ts.URL).FollowRedirect(false).Do(ctx)
@@ -61,3 +70,132 @@ func TestIndex(t *testing.T) {
 func TestAuth(t *testing.T) {
 	// panic("Not Implemented")
 }
+
+func TestNewCache(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	type args struct {
+		config map[string]any
+	}
+	tests := [...]struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"default", args{map[string]any{}}, false},
+		{"bigcache", args{map[string]any{"cache_backend": "bigcache", "cache_shards": 16}}, false},
+		{"invalid shards", args{map[string]any{"cache_backend": "bigcache", "cache_shards": 10}}, true},
+		{"redis", args{map[string]any{"cache_backend": "redis", "redis_url": "redis://" + s.Addr() + "/1"}}, false},
+		{"tiered", args{map[string]any{"cache_backend": "tiered", "redis_url": "redis://" + s.Addr()}}, false},
+		{"redis cluster", args{map[string]any{"cache_backend": "redis", "redis_url": "redis+cluster://" + s.Addr()}}, false},
+		{"tiered cluster", args{map[string]any{"cache_backend": "tiered", "redis_url": "redis+cluster://" + s.Addr()}}, false},
+		{"sentinel master required", args{map[string]any{"cache_backend": "redis", "redis_url": "redis+sentinel://" + s.Addr()}}, true},
+		{"redis url required", args{map[string]any{"cache_backend": "redis"}}, true},
+		{"invalid redis url", args{map[string]any{"cache_backend": "redis", "redis_url": "http://" + s.Addr()}}, true},
+		{"redis unreachable", args{map[string]any{"cache_backend": "redis", "redis_url": "redis://127.0.0.1:1"}}, true},
+		{"disk", args{map[string]any{"cache_backend": "disk", "cache_path": filepath.Join(t.TempDir(), "cache.db")}}, false},
+		{"disk by path", args{map[string]any{"cache_path": filepath.Join(t.TempDir(), "cache.db")}}, false},
+		{"disk path required", args{map[string]any{"cache_backend": "disk"}}, true},
+		{"memcache servers required", args{map[string]any{"cache_backend": "memcache"}}, true},
+		{"memcache unreachable", args{map[string]any{"cache_backend": "memcache", "memcache_servers": "127.0.0.1:1"}}, true},
+		{"none", args{map[string]any{"cache_backend": "none"}}, false},
+		{"unknown", args{map[string]any{"cache_backend": "unknown"}}, true},
+		{"codec", args{map[string]any{"cache_codec": "s2"}}, false},
+		{"unknown codec", args{map[string]any{"cache_codec": "lz4"}}, true},
+		{"encrypt", args{map[string]any{"cache_encrypt": true, "cache_encryption_keys": "2:new-secret,1:old-secret"}}, false},
+		{"encrypt with secret", args{map[string]any{"cache_encrypt": true, "secret": "secret"}}, false},
+		{"encrypt without secret", args{map[string]any{"cache_encrypt": true, "secret": ""}}, true},
+		{"invalid encryption keys", args{map[string]any{"cache_encrypt": true, "cache_encryption_keys": "new-secret"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			for key, value := range tt.args.config {
+				defer viper.Set(key, viper.Get(key))
+				viper.Set(key, value)
+			}
+
+			got, err := newCache(ctx, cache.NewKeyBuilder("test", 1, "").Scope)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, got.Set(ctx, "key", []byte("value")))
+		})
+	}
+}
+
+func TestTrashStore(t *testing.T) {
+	s := miniredis.RunT(t)
+
+	type args struct {
+		config map[string]any
+	}
+	tests := [...]struct {
+		name string
+		args args
+		want TrashStore
+	}{
+		{"none", args{map[string]any{"cache_backend": "none"}}, &fileTrashStore{}},
+		{"bigcache", args{map[string]any{"cache_backend": "bigcache"}}, &fileTrashStore{}},
+		{"redis", args{map[string]any{"cache_backend": "redis", "redis_url": "redis://" + s.Addr(), "cache_encrypt": true}}, &cacheTrashStore{}},
+		{"tiered", args{map[string]any{"cache_backend": "tiered", "redis_url": "redis://" + s.Addr(), "cache_encrypt": true}}, &cacheTrashStore{}},
+		{"redis without encryption", args{map[string]any{"cache_backend": "redis", "redis_url": "redis://" + s.Addr()}}, &fileTrashStore{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			s.FlushAll()
+
+			config := map[string]any{"delete_grace_period": time.Minute, "trash_file": filepath.Join(t.TempDir(), "trash.json"), "secret": "secret", "cache_encrypt": false}
+			maps.Copy(config, tt.args.config)
+			for key, value := range config {
+				defer viper.Set(key, viper.Get(key))
+				viper.Set(key, value)
+			}
+
+			svc, err := newPocketService(ctx, "http://127.0.0.1")
+			require.NoError(t, err)
+			require.IsType(t, tt.want, svc.trash.store)
+
+			newPocketStub(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"status": 1, "list": {"1234": {"item_id": "1234", "given_url": "https://example.com/"}}}`))
+			})
+
+			// queued deletion is kept even without cache, so it is flushed and can be undone
+			entries, err := svc.deleteArticles(ctx, "token", "1234")
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+
+			users, err := svc.trash.Users(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []string{"token"}, users)
+
+			entries, err = svc.trash.List(ctx, "token")
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+
+			// commands share trash with the server
+			trash, err := OpenTrash(ctx)
+			require.NoError(t, err)
+			require.IsType(t, tt.want, trash.store)
+			entries, err = trash.List(ctx, "token")
+			require.NoError(t, err)
+			require.Len(t, entries, 1, "deletion of server should be seen by commands")
+		})
+	}
+}
+
+func TestDictionarySamples(t *testing.T) {
+	samples, err := DictionarySamples(sampleFavorites(1000))
+	require.NoError(t, err)
+	require.Len(t, samples, 1000/dictionarySampleSize)
+
+	_, err = cache.TrainZstdDictionary(samples)
+	require.NoError(t, err)
+}
@@ -0,0 +1,448 @@
+package pocket
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/whitekid/getpocket"
+	"github.com/whitekid/goxp/log"
+	"golang.org/x/exp/maps"
+
+	"pocket-pick/config"
+	"pocket-pick/pkg/cache"
+)
+
+// setupArticleRoute setup article resource api
+func (s *pocketService) setupArticleRoute(g *echo.Group) {
+	g.POST("", s.handlePostArticles)
+	g.POST("/batch", s.handlePostArticlesBatch)
+	g.DELETE("/:item_id", s.handleDeleteArticle)
+	g.POST("/:item_id/archive", s.handlePostArticleArchive)
+	g.POST("/:item_id/readd", s.handlePostArticleReadd)
+	g.POST("/:item_id/favorite", s.handlePostArticleFavorite)
+	g.POST("/:item_id/unfavorite", s.handlePostArticleUnfavorite)
+	g.POST("/:item_id/tags", s.handlePostArticleTags)
+	g.PUT("/:item_id/tags", s.handlePutArticleTags)
+	g.DELETE("/:item_id/tags", s.handleDeleteArticleTags)
+}
+
+// setupTrashRoute setup api for articles deleted in soft-delete mode
+func (s *pocketService) setupTrashRoute(g *echo.Group) {
+	g.GET("", s.handleGetTrash)
+	g.POST("/:item_id/undo", s.handlePostTrashUndo)
+}
+
+type tagsRequest struct {
+	Tags []string `json:"tags" query:"tags"`
+}
+
+// addArticleRequest article to add; form is bound only by bookmarklet which checks csrf token
+type addArticleRequest struct {
+	URL      string   `json:"url" form:"url"`
+	Title    string   `json:"title" form:"title"`
+	Tags     []string `json:"tags" form:"tags"`
+	Favorite bool     `json:"favorite" form:"favorite"`
+}
+
+// apiAccessToken return access token of api request
+func (s *pocketService) apiAccessToken(c echo.Context) (string, error) {
+	var accessToken string
+	if err := s.requireAccessToken(c, &accessToken); err != nil {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+
+	return accessToken, nil
+}
+
+// articleRequest validate access token and item id of article api request
+func (s *pocketService) articleRequest(c echo.Context) (string, string, error) {
+	accessToken, err := s.apiAccessToken(c)
+	if err != nil {
+		return "", "", err
+	}
+
+	itemID := c.Param("item_id")
+	if _, err := strconv.Atoi(itemID); err != nil {
+		return "", "", echo.NewHTTPError(http.StatusBadRequest, "invalid item id: "+itemID)
+	}
+
+	return accessToken, itemID, nil
+}
+
+// tagsRequest bind tags from request body or query
+func (s *pocketService) tagsRequest(c echo.Context) ([]string, error) {
+	var req tagsRequest
+	if err := c.Bind(&req); err != nil {
+		return nil, err
+	}
+
+	if len(req.Tags) == 0 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "tags required")
+	}
+
+	return req.Tags, nil
+}
+
+// requireJSON reject request body which is not json
+// forms can be posted by other sites with the session cookie, forms are accepted only with csrf token, e.g. by bookmarklet
+func requireJSON(c echo.Context) error {
+	if !strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, "application/json required")
+	}
+	return nil
+}
+
+// upstreamError convert getpocket api error to http error
+func upstreamError(err error) error {
+	log.Errorf("pocket request failed: %s", err)
+	return echo.NewHTTPError(http.StatusBadGateway, "pocket request failed").SetInternal(err)
+}
+
+// pocketRequestError error of getpocket api in operations which also use local stores, e.g. trash
+type pocketRequestError struct {
+	err error
+}
+
+func (e *pocketRequestError) Error() string { return e.err.Error() }
+func (e *pocketRequestError) Unwrap() error { return e.err }
+
+// serviceError convert error of operation to http error
+// getpocket api errors are bad gateway, other errors are internal errors of local stores
+func serviceError(err error) error {
+	var pocketErr *pocketRequestError
+	if errors.As(err, &pocketErr) {
+		return upstreamError(err)
+	}
+	return err
+}
+
+// modifyArticle run modify action and response with no content
+// modified run after the action succeeded, e.g. to update cached articles
+func (s *pocketService) modifyArticle(c echo.Context, modify func(api *getpocket.Client, itemID string) *getpocket.ModifyRequest,
+	modified ...func(ctx context.Context, accessToken string, itemIDs ...string),
+) error {
+	accessToken, itemID, err := s.articleRequest(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	api := getpocket.New(config.ConsumerKey(), accessToken)
+	if err := doModify(ctx, modify(api, itemID)); err != nil {
+		return upstreamError(err)
+	}
+
+	for _, fn := range modified {
+		fn(ctx, accessToken, itemID)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// modifyArticleTags run modify action for tags and response with no content
+func (s *pocketService) modifyArticleTags(c echo.Context, modify func(api *getpocket.Client, itemID string, tags ...string) *getpocket.ModifyRequest) error {
+	accessToken, itemID, err := s.articleRequest(c)
+	if err != nil {
+		return err
+	}
+
+	tags, err := s.tagsRequest(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	api := getpocket.New(config.ConsumerKey(), accessToken)
+	if err := doModify(ctx, modify(api, itemID, tags...)); err != nil {
+		return upstreamError(err)
+	}
+
+	// tag filter reads tags of cached favorites
+	s.forgetModified(ctx, accessToken, itemID)
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// findArticle return article which has same url
+func findArticle(articles map[string]*getpocket.Article, rawURL string) *getpocket.Article {
+	for _, article := range articles {
+		if article.GivenURL == rawURL || article.ResolvedURL == rawURL {
+			return article
+		}
+	}
+	return nil
+}
+
+// save url to pocket
+func (s *pocketService) handlePostArticles(c echo.Context) error {
+	accessToken, err := s.apiAccessToken(c)
+	if err != nil {
+		return err
+	}
+
+	if err := requireJSON(c); err != nil {
+		return err
+	}
+
+	var req addArticleRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	article, err := s.addArticle(c.Request().Context(), accessToken, &req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, article)
+}
+
+// addArticle add article to pocket if it is not saved yet
+func (s *pocketService) addArticle(ctx context.Context, accessToken string, req *addArticleRequest) (*getpocket.Article, error) {
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid url: "+req.URL)
+	}
+
+	// duplicate detection with cached articles
+	articles, err := s.cachedFavorites(ctx, accessToken)
+	if err != nil && err != cache.ErrNotExists {
+		return nil, err
+	}
+
+	if article := findArticle(articles, req.URL); article != nil {
+		return nil, echo.NewHTTPError(http.StatusConflict, "article already exists: "+article.ItemID)
+	}
+
+	api := getpocket.New(config.ConsumerKey(), accessToken)
+	article, err := callPocket(ctx, api.Add(req.URL).Title(req.Title).Tags(req.Tags...).Do)
+	if err != nil {
+		return nil, upstreamError(err)
+	}
+
+	if req.Favorite {
+		if err := doModify(ctx, api.Modify().Favorite(article.ItemID)); err != nil {
+			return nil, upstreamError(err)
+		}
+	}
+
+	return article, nil
+}
+
+// deleteArticles delete articles from pocket, or queue them to trash if soft delete enabled
+// return trash entries if queued; errors of getpocket api are *pocketRequestError
+func (s *pocketService) deleteArticles(ctx context.Context, accessToken string, itemIDs ...string) ([]*TrashEntry, error) {
+	if !s.trash.Enabled() {
+		if err := doModify(ctx, getpocket.New(config.ConsumerKey(), accessToken).Modify().Delete(itemIDs...)); err != nil {
+			return nil, &pocketRequestError{errors.Wrapf(err, "articles.Delete(%s)", itemIDs)}
+		}
+		s.forgetRemoved(ctx, accessToken, itemIDs...)
+		return nil, nil
+	}
+
+	articles, err := s.articleDetails(ctx, accessToken, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.trash.Put(ctx, accessToken, articles, itemIDs...)
+	if err != nil {
+		return nil, err
+	}
+	s.forgetRemoved(ctx, accessToken, itemIDs...)
+
+	return entries, nil
+}
+
+// articleDetails return articles to restore them after deleted, from cached favorites or pocket if not cached
+func (s *pocketService) articleDetails(ctx context.Context, accessToken string, itemIDs []string) (map[string]*getpocket.Article, error) {
+	articles, err := s.cachedFavorites(ctx, accessToken)
+	if err != nil && err != cache.ErrNotExists {
+		return nil, err
+	}
+
+	if !slices.ContainsFunc(itemIDs, func(itemID string) bool { return articles[itemID] == nil }) {
+		return articles, nil
+	}
+
+	// pocket get api can not filter by item id, so all articles are fetched
+	result, err := callPocket(ctx, func(ctx context.Context) (*pocketGetResult, error) {
+		return pocketGet(ctx, accessToken, &pocketGetRequest{State: "all"})
+	})
+	if err != nil {
+		return nil, &pocketRequestError{errors.Wrap(err, "articles.Get()")}
+	}
+
+	details := make(map[string]*getpocket.Article, len(itemIDs))
+	for _, itemID := range itemIDs {
+		article := articles[itemID]
+		if article == nil {
+			article = result.Articles[itemID]
+		}
+		if article == nil {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "article not found: "+itemID)
+		}
+		details[itemID] = article
+	}
+
+	return details, nil
+}
+
+// forgetRemoved remove articles from cached articles after they are removed from favorites
+// articles are already removed from pocket, so failure is logged and cached articles are fixed by next sync
+func (s *pocketService) forgetRemoved(ctx context.Context, accessToken string, itemIDs ...string) {
+	if err := s.forgetArticles(ctx, accessToken, itemIDs...); err != nil {
+		log.Errorf("fail to remove articles from cache: %s", err)
+	}
+}
+
+// forgetModified drop cached favorites after articles are modified, e.g. tags changed or favorited
+// changed articles are fetched by delta sync on next pick; failure is logged as forgetRemoved
+func (s *pocketService) forgetModified(ctx context.Context, accessToken string, itemIDs ...string) {
+	if len(itemIDs) == 0 {
+		return
+	}
+
+	if err := s.invalidateFavorites(ctx, accessToken); err != nil {
+		log.Errorf("fail to invalidate favorites: %s", err)
+	}
+}
+
+// delete article, response with 202 if the article queued to trash
+func (s *pocketService) handleDeleteArticle(c echo.Context) error {
+	accessToken, itemID, err := s.articleRequest(c)
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.deleteArticles(c.Request().Context(), accessToken, itemID)
+	if err != nil {
+		return serviceError(err)
+	}
+
+	if len(entries) > 0 {
+		return c.JSON(http.StatusAccepted, entries[0])
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (s *pocketService) handlePostArticleArchive(c echo.Context) error {
+	return s.modifyArticle(c, func(api *getpocket.Client, itemID string) *getpocket.ModifyRequest {
+		return api.Modify().Archive(itemID)
+	}, s.forgetRemoved)
+}
+
+func (s *pocketService) handlePostArticleReadd(c echo.Context) error {
+	return s.modifyArticle(c, func(api *getpocket.Client, itemID string) *getpocket.ModifyRequest {
+		return api.Modify().Readd(itemID)
+	}, s.forgetModified)
+}
+
+func (s *pocketService) handlePostArticleFavorite(c echo.Context) error {
+	return s.modifyArticle(c, func(api *getpocket.Client, itemID string) *getpocket.ModifyRequest {
+		return api.Modify().Favorite(itemID)
+	}, s.forgetModified)
+}
+
+func (s *pocketService) handlePostArticleUnfavorite(c echo.Context) error {
+	return s.modifyArticle(c, func(api *getpocket.Client, itemID string) *getpocket.ModifyRequest {
+		return api.Modify().Unfavorite(itemID)
+	}, s.forgetRemoved)
+}
+
+// add tags to article
+func (s *pocketService) handlePostArticleTags(c echo.Context) error {
+	return s.modifyArticleTags(c, func(api *getpocket.Client, itemID string, tags ...string) *getpocket.ModifyRequest {
+		return api.Modify().TagsAdd(itemID, tags...)
+	})
+}
+
+// replace tags of article
+func (s *pocketService) handlePutArticleTags(c echo.Context) error {
+	return s.modifyArticleTags(c, func(api *getpocket.Client, itemID string, tags ...string) *getpocket.ModifyRequest {
+		return api.Modify().TagsReplace(itemID, tags...)
+	})
+}
+
+// remove tags from article
+func (s *pocketService) handleDeleteArticleTags(c echo.Context) error {
+	return s.modifyArticleTags(c, func(api *getpocket.Client, itemID string, tags ...string) *getpocket.ModifyRequest {
+		return api.Modify().TagsRemove(itemID, tags...)
+	})
+}
+
+// list articles in trash
+func (s *pocketService) handleGetTrash(c echo.Context) error {
+	accessToken, err := s.apiAccessToken(c)
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.trash.List(c.Request().Context(), accessToken)
+	if err != nil {
+		return err
+	}
+
+	if entries == nil {
+		entries = []*TrashEntry{}
+	}
+
+	return c.JSON(http.StatusOK, entries)
+}
+
+// restore article in trash
+func (s *pocketService) handlePostTrashUndo(c echo.Context) error {
+	accessToken, itemID, err := s.articleRequest(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	entry, err := s.trash.Undo(ctx, accessToken, itemID)
+	switch {
+	case errors.Is(err, ErrNotInTrash):
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrCannotRestore):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	case err != nil:
+		return upstreamError(err)
+	}
+
+	// restored article is picked again after full sync
+	if err := s.resyncFavorites(ctx, accessToken); err != nil {
+		log.Errorf("fail to resync favorites: %s", err)
+	}
+
+	return c.JSON(http.StatusOK, entry)
+}
+
+// articleURL return url of article
+func articleURL(article *getpocket.Article) string {
+	if article.ResolvedURL != "" {
+		return article.ResolvedURL
+	}
+	return article.GivenURL
+}
+
+// articleTags return sorted tag names of article
+func articleTags(article *getpocket.Article) []string {
+	tags := maps.Keys(article.Tags)
+	slices.Sort(tags)
+	return tags
+}
+
+// status of articles
+const (
+	articleStatusUnread   = "0"
+	articleStatusArchived = "1"
+	articleStatusDeleted  = "2"
+)
+
+// isFavorite return true if article is marked as favorite
+func isFavorite(article *getpocket.Article) bool { return article.Favorite == "1" }
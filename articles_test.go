@@ -0,0 +1,131 @@
+package pocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArticleAPIUnauthorized(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ts := newTestServer(t, ctx)
+
+	type args struct {
+		method string
+		path   string
+	}
+	tests := [...]struct {
+		name string
+		args args
+	}{
+		{"delete", args{http.MethodDelete, "/api/v1/articles/1234"}},
+		{"archive", args{http.MethodPost, "/api/v1/articles/1234/archive"}},
+		{"readd", args{http.MethodPost, "/api/v1/articles/1234/readd"}},
+		{"favorite", args{http.MethodPost, "/api/v1/articles/1234/favorite"}},
+		{"unfavorite", args{http.MethodPost, "/api/v1/articles/1234/unfavorite"}},
+		{"tags add", args{http.MethodPost, "/api/v1/articles/1234/tags"}},
+		{"tags replace", args{http.MethodPut, "/api/v1/articles/1234/tags"}},
+		{"tags remove", args{http.MethodDelete, "/api/v1/articles/1234/tags"}},
+		{"add", args{http.MethodPost, "/api/v1/articles"}},
+		{"batch", args{http.MethodPost, "/api/v1/articles/batch"}},
+		{"trash", args{http.MethodGet, "/api/v1/trash"}},
+		{"undo", args{http.MethodPost, "/api/v1/trash/1234/undo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(ctx, tt.args.method, ts.URL+tt.args.path, nil)
+			require.NoError(t, err)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body), "error body should be json")
+			require.NotEmpty(t, body["message"])
+		})
+	}
+}
+
+func TestDeleteArticlesDetails(t *testing.T) {
+	ctx := context.Background()
+
+	defer viper.Set("delete_grace_period", viper.Get("delete_grace_period"))
+	viper.Set("delete_grace_period", time.Minute)
+	s := newTestService(t)
+
+	// not favorite article is not cached, its detail is fetched from pocket
+	newPocketStub(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/get", r.URL.Path)
+
+		var req pocketGetRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "all", req.State)
+
+		w.Write([]byte(`{"status": 1, "list": {"1234": {"item_id": "1234", "given_url": "https://example.com/", "status": "0"}}}`))
+	})
+
+	entries, err := s.deleteArticles(ctx, "token", "1234")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "https://example.com/", entries[0].URL, "deleted article should be restorable")
+
+	_, err = s.deleteArticles(ctx, "token", "5678")
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusNotFound, httpErr.Code)
+}
+
+func TestServiceError(t *testing.T) {
+	err := serviceError(&pocketRequestError{errors.New("pocket failed")})
+	var httpErr *echo.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	require.Equal(t, http.StatusBadGateway, httpErr.Code)
+
+	storeErr := errors.New("trash store failed")
+	require.Equal(t, storeErr, serviceError(storeErr), "errors of local stores are internal errors")
+}
+
+func TestRequireJSON(t *testing.T) {
+	type args struct {
+		contentType string
+	}
+	tests := [...]struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"json", args{"application/json"}, false},
+		{"json with charset", args{"application/json; charset=UTF-8"}, false},
+		{"form", args{"application/x-www-form-urlencoded"}, true},
+		{"multipart", args{"multipart/form-data; boundary=x"}, true},
+		{"text", args{"text/plain"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/articles", strings.NewReader(`{}`))
+			req.Header.Set(echo.HeaderContentType, tt.args.contentType)
+			err := requireJSON(echo.New().NewContext(req, httptest.NewRecorder()))
+			if tt.wantErr {
+				var httpErr *echo.HTTPError
+				require.ErrorAs(t, err, &httpErr)
+				require.Equal(t, http.StatusUnsupportedMediaType, httpErr.Code)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package pocket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/whitekid/goxp/log"
+)
+
+const (
+	batchChunkSize = 100  // actions per pocket modify request
+	batchMaxItems  = 1000 // max items per batch request
+)
+
+// batch actions
+const (
+	BatchDelete   = "delete"
+	BatchArchive  = "archive"
+	BatchTag      = "tag"
+	BatchFavorite = "favorite"
+)
+
+// BatchAction action to apply to articles
+type BatchAction struct {
+	Action  string   `json:"action"`
+	ItemIDs []string `json:"item_ids"`
+	Tags    []string `json:"tags,omitempty"` // for tag action
+}
+
+// BatchResult result of action for each article
+type BatchResult struct {
+	ItemID string `json:"item_id"`
+	Action string `json:"action"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+type batchRequest struct {
+	Actions []*BatchAction `json:"actions"`
+}
+
+type batchResponse struct {
+	Results []*BatchResult `json:"results"`
+}
+
+func (a *BatchAction) validate() error {
+	if !slices.Contains([]string{BatchDelete, BatchArchive, BatchTag, BatchFavorite}, a.Action) {
+		return fmt.Errorf("unknown action: %s", a.Action)
+	}
+
+	if len(a.ItemIDs) == 0 {
+		return fmt.Errorf("item_ids required: %s", a.Action)
+	}
+
+	for _, itemID := range a.ItemIDs {
+		if _, err := strconv.Atoi(itemID); err != nil {
+			return fmt.Errorf("invalid item id: %s", itemID)
+		}
+	}
+
+	if a.Action == BatchTag && len(a.Tags) == 0 {
+		return fmt.Errorf("tags required: %s", a.Action)
+	}
+
+	return nil
+}
+
+// pocketAction return send api action for item
+func (a *BatchAction) pocketAction(itemID string) *pocketAction {
+	switch a.Action {
+	case BatchDelete, BatchArchive, BatchFavorite:
+		return &pocketAction{Action: a.Action, ItemID: itemID}
+	case BatchTag:
+		return &pocketAction{Action: "tags_add", ItemID: itemID, Tags: strings.Join(a.Tags, ",")}
+	}
+
+	panic("unknown action: " + a.Action)
+}
+
+type batchOp struct {
+	action *BatchAction
+	result *BatchResult
+}
+
+// RunBatch run actions with pocket send api, splitted into chunks of batchChunkSize
+// return result for each item in the order of actions, as pocket reported for each action
+func RunBatch(ctx context.Context, accessToken string, actions ...*BatchAction) []*BatchResult {
+	var ops []*batchOp
+	for _, action := range actions {
+		for _, itemID := range action.ItemIDs {
+			ops = append(ops, &batchOp{
+				action: action,
+				result: &BatchResult{ItemID: itemID, Action: action.Action},
+			})
+		}
+	}
+
+	for start := 0; start < len(ops); start += batchChunkSize {
+		chunk := ops[start:min(start+batchChunkSize, len(ops))]
+
+		send := make([]*pocketAction, len(chunk))
+		for i, op := range chunk {
+			send[i] = op.action.pocketAction(op.result.ItemID)
+		}
+
+		resp, err := callPocket(ctx, func(ctx context.Context) (*pocketSendResponse, error) { return pocketSend(ctx, accessToken, send) })
+		if err != nil {
+			log.Errorf("batch modify failed: %s", err)
+		}
+
+		for i, op := range chunk {
+			switch {
+			case err != nil:
+				op.result.Error = err.Error()
+			case resp.succeeded(i):
+				op.result.OK = true
+			default:
+				op.result.Error = resp.actionError(i)
+			}
+		}
+	}
+
+	results := make([]*BatchResult, len(ops))
+	for i, op := range ops {
+		results[i] = op.result
+	}
+	return results
+}
+
+// RemovedItems return items deleted or archived by succeeded actions, which are not picked any more
+func RemovedItems(results []*BatchResult) []string {
+	return succeededItems(results, BatchDelete, BatchArchive)
+}
+
+// succeededItems return items of succeeded actions
+func succeededItems(results []*BatchResult, actions ...string) []string {
+	var itemIDs []string
+	for _, result := range results {
+		if result.OK && slices.Contains(actions, result.Action) {
+			itemIDs = append(itemIDs, result.ItemID)
+		}
+	}
+	return itemIDs
+}
+
+// run actions on many articles
+func (s *pocketService) handlePostArticlesBatch(c echo.Context) error {
+	accessToken, err := s.apiAccessToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req batchRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	if len(req.Actions) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "actions required")
+	}
+
+	items := 0
+	for _, action := range req.Actions {
+		if err := action.validate(); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		items += len(action.ItemIDs)
+	}
+
+	if items > batchMaxItems {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("too many items: %d > %d", items, batchMaxItems))
+	}
+
+	ctx := c.Request().Context()
+	var results []*BatchResult
+
+	// deletions go to trash if soft delete enabled
+	if s.trash.Enabled() {
+		var actions []*BatchAction
+		for _, action := range req.Actions {
+			if action.Action != BatchDelete {
+				actions = append(actions, action)
+				continue
+			}
+
+			_, err := s.deleteArticles(ctx, accessToken, action.ItemIDs...)
+			for _, itemID := range action.ItemIDs {
+				result := &BatchResult{ItemID: itemID, Action: action.Action, OK: err == nil}
+				if err != nil {
+					result.Error = err.Error()
+				}
+				results = append(results, result)
+			}
+		}
+		req.Actions = actions
+	}
+
+	// deletions to trash are already removed from cached favorites by deleteArticles
+	batchResults := RunBatch(ctx, accessToken, req.Actions...)
+	s.forgetRemoved(ctx, accessToken, RemovedItems(batchResults)...)
+	s.forgetModified(ctx, accessToken, succeededItems(batchResults, BatchTag, BatchFavorite)...)
+	results = append(results, batchResults...)
+
+	return c.JSON(http.StatusOK, &batchResponse{Results: results})
+}
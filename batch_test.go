@@ -0,0 +1,128 @@
+package pocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchActionValidate(t *testing.T) {
+	type args struct {
+		action *BatchAction
+	}
+	tests := [...]struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{"delete", args{&BatchAction{Action: BatchDelete, ItemIDs: []string{"1", "2"}}}, false},
+		{"archive", args{&BatchAction{Action: BatchArchive, ItemIDs: []string{"1"}}}, false},
+		{"favorite", args{&BatchAction{Action: BatchFavorite, ItemIDs: []string{"1"}}}, false},
+		{"tag", args{&BatchAction{Action: BatchTag, ItemIDs: []string{"1"}, Tags: []string{"go"}}}, false},
+		{"tag without tags", args{&BatchAction{Action: BatchTag, ItemIDs: []string{"1"}}}, true},
+		{"unknown action", args{&BatchAction{Action: "readd", ItemIDs: []string{"1"}}}, true},
+		{"no items", args{&BatchAction{Action: BatchDelete}}, true},
+		{"invalid item id", args{&BatchAction{Action: BatchDelete, ItemIDs: []string{"1", "abc"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.args.action.validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRemovedItems(t *testing.T) {
+	results := []*BatchResult{
+		{ItemID: "1", Action: BatchDelete, OK: true},
+		{ItemID: "2", Action: BatchDelete},
+		{ItemID: "3", Action: BatchArchive, OK: true},
+		{ItemID: "4", Action: BatchTag, OK: true},
+	}
+
+	require.Equal(t, []string{"1", "3"}, RemovedItems(results))
+}
+
+// newPocketStub serve getpocket api with handler while the test runs
+func newPocketStub(t *testing.T, handler http.HandlerFunc) {
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+
+	apiURL := pocketAPIURL
+	pocketAPIURL = ts.URL
+	t.Cleanup(func() { pocketAPIURL = apiURL })
+}
+
+func TestRunBatch(t *testing.T) {
+	var requests []*pocketSendRequest
+	newPocketStub(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/send", r.URL.Path)
+
+		var req pocketSendRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, &req)
+
+		// item 13 fails
+		resp := &pocketSendResponse{Status: 1}
+		for _, action := range req.Actions {
+			if action.ItemID == "13" {
+				resp.ActionResults = append(resp.ActionResults, json.RawMessage("false"))
+				resp.ActionErrors = append(resp.ActionErrors, &pocketError{Message: "Invalid item id", Code: 422})
+				continue
+			}
+			resp.ActionResults = append(resp.ActionResults, json.RawMessage("true"))
+			resp.ActionErrors = append(resp.ActionErrors, nil)
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	})
+
+	var itemIDs []string
+	for i := 0; i < batchChunkSize+10; i++ {
+		itemIDs = append(itemIDs, fmt.Sprintf("%d", i))
+	}
+
+	results := RunBatch(context.Background(), "token",
+		&BatchAction{Action: BatchDelete, ItemIDs: itemIDs},
+		&BatchAction{Action: BatchTag, ItemIDs: []string{"1"}, Tags: []string{"go", "rust"}},
+	)
+	require.Len(t, results, len(itemIDs)+1)
+	require.Len(t, requests, 2, "splitted into chunks")
+	require.Len(t, requests[0].Actions, batchChunkSize)
+	require.Equal(t, &pocketAction{Action: "tags_add", ItemID: "1", Tags: "go,rust"}, requests[1].Actions[10])
+
+	for _, result := range results {
+		if result.ItemID == "13" {
+			require.False(t, result.OK)
+			require.Equal(t, "Invalid item id", result.Error)
+			continue
+		}
+		require.True(t, result.OK, result.ItemID)
+		require.Empty(t, result.Error)
+	}
+
+	require.NotContains(t, RemovedItems(results), "13")
+	require.Len(t, RemovedItems(results), len(itemIDs)-1)
+}
+
+func TestRunBatchFailed(t *testing.T) {
+	newPocketStub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Error", "Invalid consumer key.")
+		w.WriteHeader(http.StatusForbidden)
+	})
+
+	results := RunBatch(context.Background(), "token", &BatchAction{Action: BatchArchive, ItemIDs: []string{"1", "2"}})
+	require.Len(t, results, 2)
+	for _, result := range results {
+		require.False(t, result.OK)
+		require.Contains(t, result.Error, "Invalid consumer key.")
+	}
+}
@@ -0,0 +1,87 @@
+package pocket
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/pkg/errors"
+)
+
+// setupBookmarkletRoute setup save page opened by bookmarklet
+func (s *pocketService) setupBookmarkletRoute(g *echo.Group) {
+	g.GET("", s.handleGetBookmarklet)
+	g.POST("", s.handlePostBookmarklet)
+}
+
+// bookmarkletCSRF check csrf token of the save page, so other sites can not save articles by posting to it
+func bookmarkletCSRF() echo.MiddlewareFunc {
+	return middleware.CSRFWithConfig(middleware.CSRFConfig{
+		TokenLookup:    "form:_csrf",
+		CookiePath:     "/bookmarklet",
+		CookieHTTPOnly: true,
+		CookieSameSite: http.SameSiteStrictMode,
+	})
+}
+
+// bookmarkletScript return javascript which open the save page with current page
+func (s *pocketService) bookmarkletScript(favorite bool) template.URL {
+	return template.URL(fmt.Sprintf(`javascript:(function(){window.open(%q+'?favorite=%t&url='+encodeURIComponent(location.href)+'&title='+encodeURIComponent(document.title),'pocket-pick','width=480,height=240')})()`,
+		s.rootURL+"/bookmarklet", favorite))
+}
+
+// show bookmarklets for user
+func (s *pocketService) handleGetSettingsBookmarklet(c echo.Context) error {
+	var accessToken string
+	if err := s.requireAccessToken(c, &accessToken); err != nil {
+		return c.Redirect(http.StatusFound, s.rootURL)
+	}
+
+	return render(c, http.StatusOK, "settings-bookmarklet", map[string]any{
+		"Save":         s.bookmarkletScript(false),
+		"SaveFavorite": s.bookmarkletScript(true),
+	})
+}
+
+// save page opened by bookmarklet, the article is saved when user click save button
+func (s *pocketService) handleGetBookmarklet(c echo.Context) error {
+	return render(c, http.StatusOK, "bookmarklet", map[string]any{
+		"CSRF":     c.Get(middleware.DefaultCSRFConfig.ContextKey),
+		"URL":      c.QueryParam("url"),
+		"Title":    c.QueryParam("title"),
+		"Favorite": c.QueryParam("favorite") == "true",
+	})
+}
+
+// save article posted from the save page
+func (s *pocketService) handlePostBookmarklet(c echo.Context) error {
+	accessToken, err := s.apiAccessToken(c)
+	if err != nil {
+		return err
+	}
+
+	var req addArticleRequest
+	if err := c.Bind(&req); err != nil {
+		return err
+	}
+
+	code, status := http.StatusCreated, "Saved."
+	if _, err := s.addArticle(c.Request().Context(), accessToken, &req); err != nil {
+		var httpErr *echo.HTTPError
+		if !errors.As(err, &httpErr) {
+			return err
+		}
+
+		code, status = httpErr.Code, fmt.Sprintf("Save failed: %v", httpErr.Message)
+		if code == http.StatusConflict {
+			status = "Already saved."
+		}
+	}
+
+	return render(c, code, "bookmarklet-saved", map[string]any{
+		"Status": status,
+		"Saved":  code == http.StatusCreated,
+	})
+}
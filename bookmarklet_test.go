@@ -0,0 +1,82 @@
+package pocket
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBookmarklet(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ts := newTestServer(t, ctx)
+
+	resp, err := http.Get(ts.URL + "/bookmarklet?url=https%3A%2F%2Fexample.com%2F&title=example&favorite=true")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `name="url" value="https://example.com/"`)
+	require.Contains(t, string(body), `name="favorite" value="true"`)
+	require.Contains(t, string(body), `<button type="submit">Save</button>`)
+	require.NotContains(t, string(body), "fetch(", "article should be saved only by clicking save")
+	require.Regexp(t, `name="_csrf" value="\w+"`, string(body))
+}
+
+func TestBookmarkletCSRF(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ts := newTestServer(t, ctx)
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{Jar: jar}
+
+	resp, err := client.Get(ts.URL + "/bookmarklet?url=https%3A%2F%2Fexample.com%2F")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	type args struct {
+		form url.Values
+	}
+	tests := [...]struct {
+		name     string
+		args     args
+		wantCode int
+	}{
+		{"without token", args{url.Values{"url": {"https://example.com/"}}}, http.StatusBadRequest},
+		{"invalid token", args{url.Values{"url": {"https://example.com/"}, "_csrf": {"forged"}}}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.PostForm(ts.URL+"/bookmarklet", tt.args.form)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, tt.wantCode, resp.StatusCode)
+		})
+	}
+}
+
+func TestSettingsBookmarkletRequireLogin(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	ts := newTestServer(t, ctx)
+
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(ts.URL + "/settings/bookmarklet")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+}
@@ -0,0 +1,151 @@
+package pocket
+
+import (
+	"context"
+	"expvar"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"github.com/sony/gobreaker"
+	"github.com/whitekid/getpocket"
+	"github.com/whitekid/goxp/log"
+
+	"pocket-pick/config"
+)
+
+const headerDegraded = "X-Pocket-Pick-Degraded"
+
+var (
+	metricBreakerState = expvar.NewString("pocket_breaker_state")
+	metricDegraded     = expvar.NewInt("pocket_degraded_responses")
+)
+
+// pocketBreaker circuit breaker for all getpocket api calls
+var pocketBreaker = sync.OnceValue(func() *gobreaker.CircuitBreaker {
+	metricBreakerState.Set(gobreaker.StateClosed.String())
+
+	return gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        "getpocket",
+		MaxRequests: 1,
+		Timeout:     config.BreakerTimeout(),
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= uint32(config.BreakerFailures())
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			log.Warnf("circuit breaker %s: %s -> %s", name, from, to)
+			metricBreakerState.Set(to.String())
+		},
+		IsSuccessful: func(err error) bool { return !pocketFailure(err) },
+	})
+})
+
+// pocketStatusPattern status code in error messages of getpocket client, which does not return typed errors
+var pocketStatusPattern = regexp.MustCompile(`(?i)\bstatus(?: code)?\W{0,3}([1-5]\d\d)\b`)
+
+// pocketFailure return true if err means getpocket api is not available: network errors, timeouts and server errors
+// client errors such as invalid access token of a user are not counted, so they don't open the breaker for every user
+func pocketFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	if code := pocketStatusCode(err); code != 0 {
+		return code >= http.StatusInternalServerError
+	}
+
+	return true
+}
+
+// pocketStatusCode return http status code of getpocket api error, 0 if unknown
+func pocketStatusCode(err error) int {
+	var statusErr *pocketStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+
+	var coder interface{ StatusCode() int }
+	if errors.As(err, &coder) {
+		return coder.StatusCode()
+	}
+
+	if m := pocketStatusPattern.FindStringSubmatch(err.Error()); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		return code
+	}
+
+	return 0
+}
+
+// callPocket call getpocket api through circuit breaker with timeout
+func callPocket[T any](ctx context.Context, fn func(ctx context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, config.PocketTimeout())
+	defer cancel()
+
+	v, err := pocketBreaker().Execute(func() (interface{}, error) { return fn(ctx) })
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	r, _ := v.(T)
+	return r, nil
+}
+
+// doModify run modify request through circuit breaker
+func doModify(ctx context.Context, modify *getpocket.ModifyRequest) error {
+	_, err := callPocket(ctx, modify.Do)
+	return err
+}
+
+// pocketDegraded return true if getpocket api is not available
+func pocketDegraded() bool { return pocketBreaker().State() != gobreaker.StateClosed }
+
+// degradedMiddleware mark response as degraded while getpocket api is not available
+func degradedMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if pocketDegraded() {
+			c.Response().Header().Set(headerDegraded, "true")
+		}
+		return next(c)
+	}
+}
+
+// probePocket probe getpocket api periodically to close the circuit breaker
+func (s *pocketService) probePocket(ctx context.Context) {
+	ticker := time.NewTicker(config.BreakerTimeout())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if pocketBreaker().State() != gobreaker.StateHalfOpen {
+			continue
+		}
+
+		if _, err := callPocket(ctx, func(ctx context.Context) (string, error) {
+			requestToken, _, err := getpocket.New(config.ConsumerKey(), "").AuthorizedURL(ctx, s.rootURL+"/auth")
+			return requestToken, err
+		}); err != nil {
+			log.Warnf("probe pocket failed: %s", err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package pocket
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPocketFailure(t *testing.T) {
+	type args struct {
+		err error
+	}
+	tests := [...]struct {
+		name string
+		args args
+		want bool
+	}{
+		{"success", args{nil}, false},
+		{"canceled", args{context.Canceled}, false},
+		{"timeout", args{errors.Wrap(context.DeadlineExceeded, "get")}, true},
+		{"network", args{&net.OpError{Op: "dial", Err: errors.New("connection refused")}}, true},
+		{"server error", args{&pocketStatusError{Path: "/get", StatusCode: http.StatusServiceUnavailable}}, true},
+		{"unauthorized", args{errors.Wrap(&pocketStatusError{Path: "/get", StatusCode: http.StatusUnauthorized}, "get")}, false},
+		{"bad request", args{&pocketStatusError{Path: "/send", StatusCode: http.StatusBadRequest}}, false},
+		{"client message", args{errors.New("request failed with status 403: Access denied")}, false},
+		{"server message", args{errors.New("request failed with status code 502")}, true},
+		{"unknown", args{errors.New("unexpected response")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, pocketFailure(tt.args.err))
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/whitekid/getpocket"
+	"github.com/whitekid/goxp/log"
+	"github.com/whitekid/goxp/request"
+	"golang.org/x/exp/maps"
+
+	pocket "pocket-pick"
+	"pocket-pick/config"
+	"pocket-pick/pkg/cache"
+)
+
+func init() {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "inspect cache of server with admin api",
+	}
+
+	var output string
+	trainCmd := &cobra.Command{
+		Use:          "train-dict",
+		Short:        "train zstd dictionary for cache_zstd_dict from favorite articles",
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         func(cmd *cobra.Command, args []string) error { return cacheTrainDict(cmd.Context(), output) },
+	}
+	trainCmd.Flags().StringVarP(&output, "output", "o", "zstd.dict", "dictionary file")
+
+	var force bool
+	flushCmd := &cobra.Command{
+		Use:          "flush prefix",
+		Short:        "delete cache keys which start with prefix, trash, sync state and locks are kept without --force",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE:         func(cmd *cobra.Command, args []string) error { return cacheFlush(cmd.Context(), args[0], force) },
+	}
+	flushCmd.Flags().BoolVar(&force, "force", false, "delete trash, sync state and locks too")
+
+	cacheCmd.AddCommand(
+		&cobra.Command{
+			Use:          "stats",
+			Short:        "show cache stats",
+			SilenceUsage: true,
+			RunE:         func(cmd *cobra.Command, args []string) error { return cacheStats(cmd.Context()) },
+		},
+		&cobra.Command{
+			Use:          "keys [prefix]",
+			Short:        "list cache keys which start with prefix",
+			Args:         cobra.MaximumNArgs(1),
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return cacheKeys(cmd.Context(), strings.Join(args, ""))
+			},
+		},
+		&cobra.Command{
+			Use:          "inspect key",
+			Short:        "show size and ttl of cached value",
+			Args:         cobra.ExactArgs(1),
+			SilenceUsage: true,
+			RunE:         func(cmd *cobra.Command, args []string) error { return cacheInspect(cmd.Context(), args[0]) },
+		},
+		flushCmd,
+		trainCmd,
+	)
+
+	rootCmd.AddCommand(cacheCmd)
+}
+
+// openArticleCache return articles cached by server, nil if the cache is not shared with the server or can not be opened
+// commands work without cache, cached articles are updated with admin api or by next sync of server
+func openArticleCache(ctx context.Context) *pocket.ArticleCache {
+	c, err := pocket.NewArticleCache(ctx)
+	if err != nil {
+		if err != pocket.ErrCacheNotShared {
+			log.Warnf("fail to open cache: %s", err)
+		}
+		return nil
+	}
+	return c
+}
+
+// forgetArticles remove articles from articles cached by server
+// the server is asked with admin api if admin_token is set, otherwise the cache is updated directly if it is shared with the server
+// articles are already removed from pocket, so failure is logged and cached articles are fixed by next sync
+func forgetArticles(ctx context.Context, c *pocket.ArticleCache, itemIDs ...string) {
+	if len(itemIDs) == 0 {
+		return
+	}
+
+	updateArticleCache(ctx, "/favorites/forget", url.Values{"item_id": itemIDs}, c, func(c *pocket.ArticleCache) error {
+		return c.Forget(ctx, config.AccessToken(), itemIDs...)
+	})
+}
+
+// resyncArticles drop articles cached by server, so restored articles are picked again
+func resyncArticles(ctx context.Context, c *pocket.ArticleCache) {
+	updateArticleCache(ctx, "/favorites/resync", url.Values{}, c, func(c *pocket.ArticleCache) error {
+		return c.Resync(ctx, config.AccessToken())
+	})
+}
+
+// updateArticleCache update articles cached by server with admin api, or with shared cache if admin api is not available
+func updateArticleCache(ctx context.Context, path string, form url.Values, c *pocket.ArticleCache, update func(c *pocket.ArticleCache) error) {
+	if config.AdminToken() != "" {
+		form.Set("access_token", config.AccessToken())
+		err := adminRequest(ctx, http.MethodPost, path, form, nil)
+		if err == nil {
+			return
+		}
+		log.Warnf("fail to update cached articles with admin api: %s", err)
+	}
+
+	if c == nil {
+		log.Infof("cached articles of server are updated by next sync, set admin_token to update them now")
+		return
+	}
+
+	if err := update(c); err != nil {
+		log.Warnf("fail to update cached articles: %s", err)
+	}
+}
+
+// adminRequest call admin api of server at root_url and decode json response to v
+// query is sent as form for post, response is not decoded if v is nil
+func adminRequest(ctx context.Context, method string, path string, query url.Values, v any) error {
+	if config.AdminToken() == "" {
+		return errors.New("admin_token required")
+	}
+
+	u := fmt.Sprintf("%s/admin%s", strings.TrimSuffix(config.RootURL(), "/"), path)
+
+	var req *request.Request
+	switch method {
+	case http.MethodPost:
+		req = request.Post("%s", u)
+		for key, values := range query {
+			for _, value := range values {
+				req = req.Form(key, value)
+			}
+		}
+	case http.MethodDelete:
+		req = request.Delete("%s?%s", u, query.Encode())
+	default:
+		req = request.Get("%s?%s", u, query.Encode())
+	}
+
+	resp, err := req.Header("Authorization", "Bearer "+config.AdminToken()).Do(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s", method, path)
+	}
+
+	if err := resp.Success(); err != nil {
+		return errors.Wrapf(err, "%s %s failed with %d", method, path, resp.StatusCode)
+	}
+
+	if v == nil {
+		return resp.Body.Close()
+	}
+	return resp.JSON(v)
+}
+
+func cacheStats(ctx context.Context) error {
+	var stats cache.Stats
+	if err := adminRequest(ctx, http.MethodGet, "/cache/stats", nil, &stats); err != nil {
+		return err
+	}
+
+	fmt.Printf("hits\t%d\n", stats.Hits)
+	fmt.Printf("misses\t%d\n", stats.Misses)
+	fmt.Printf("hit ratio\t%.2f\n", stats.HitRatio())
+	fmt.Printf("errors\t%d\n", stats.Errors)
+	fmt.Printf("bytes read\t%d\n", stats.BytesRead)
+	fmt.Printf("bytes written\t%d\n", stats.BytesWritten)
+
+	ops := maps.Keys(stats.Latency)
+	slices.Sort(ops)
+	for _, op := range ops {
+		l := stats.Latency[op]
+		if l.Calls == 0 {
+			continue
+		}
+		fmt.Printf("latency %s\tcalls=%d avg=%s max=%s\n", op, l.Calls, l.Total/time.Duration(l.Calls), l.Max)
+	}
+
+	names := maps.Keys(stats.Backend)
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Printf("backend %s\t%d\n", name, stats.Backend[name])
+	}
+
+	return nil
+}
+
+func cacheKeys(ctx context.Context, prefix string) error {
+	var keys []string
+	if err := adminRequest(ctx, http.MethodGet, "/cache/keys", url.Values{"prefix": {prefix}}, &keys); err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		fmt.Println(key)
+	}
+	return nil
+}
+
+func cacheInspect(ctx context.Context, key string) error {
+	var entry pocket.CacheEntry
+	if err := adminRequest(ctx, http.MethodGet, "/cache/entry", url.Values{"key": {key}}, &entry); err != nil {
+		return err
+	}
+
+	fmt.Printf("key\t%s\n", entry.Key)
+	fmt.Printf("size\t%d\n", entry.Size)
+	fmt.Printf("ttl\t%s\n", entry.TTL)
+	return nil
+}
+
+func cacheFlush(ctx context.Context, prefix string, force bool) error {
+	var result pocket.CacheFlushResult
+	query := url.Values{"prefix": {prefix}, "force": {strconv.FormatBool(force)}}
+	if err := adminRequest(ctx, http.MethodDelete, "/cache/keys", query, &result); err != nil {
+		return err
+	}
+
+	log.Infof("%d keys deleted", result.Deleted)
+	if result.Kept > 0 {
+		log.Infof("%d trash, sync state and lock keys kept, use --force to delete them", result.Kept)
+	}
+	return nil
+}
+
+// cacheTrainDict train zstd dictionary with favorite articles serialized as they are cached
+func cacheTrainDict(ctx context.Context, output string) error {
+	api := getpocket.New(config.ConsumerKey(), config.AccessToken())
+	items, err := api.Articles().Get().Favorite(getpocket.Favorited).Do(ctx)
+	if err != nil {
+		return errors.Wrap(err, "articles.Get(Favorite)")
+	}
+
+	samples, err := pocket.DictionarySamples(items)
+	if err != nil {
+		return err
+	}
+
+	dictionary, err := cache.TrainZstdDictionary(samples)
+	if err != nil {
+		return errors.Wrapf(err, "train dictionary with %d articles", len(items))
+	}
+
+	if err := os.WriteFile(output, dictionary, 0o600); err != nil {
+		return err
+	}
+
+	log.Infof("%d bytes dictionary trained with %d articles, set cache_zstd_dict=%s", len(dictionary), len(items), output)
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"slices"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -12,9 +13,14 @@ import (
 	"github.com/whitekid/goxp/request"
 	"github.com/whitekid/iter"
 
+	pocket "pocket-pick"
 	"pocket-pick/config"
+	"pocket-pick/pkg/cache"
 )
 
+// deadLinkLockTTL lease of dead link check, renewed while checking
+const deadLinkLockTTL = time.Minute
+
 func init() {
 	rootCmd.AddCommand(&cobra.Command{
 		Use:  "check-dead-link",
@@ -24,6 +30,23 @@ func init() {
 }
 
 func checkDeadLink(ctx context.Context) error {
+	// run on only one host at a time if cache is shared
+	articleCache := openArticleCache(ctx)
+	if articleCache != nil {
+		lock, err := articleCache.TryLock(ctx, config.AccessToken(), "check-dead-link", deadLinkLockTTL)
+		if err != nil {
+			if err == cache.ErrLocked {
+				return errors.New("dead link check is running on other host")
+			}
+			return errors.Wrap(err, "lock failed")
+		}
+		defer lock.Unlock(context.WithoutCancel(ctx))
+
+		var cancel context.CancelFunc
+		ctx, cancel = lock.KeepAlive(ctx)
+		defer cancel()
+	}
+
 	api := getpocket.New(config.ConsumerKey(), config.AccessToken())
 	items, err := api.Articles().Get().Favorite(getpocket.Favorited).Do(ctx)
 	if err != nil {
@@ -72,8 +95,18 @@ func checkDeadLink(ctx context.Context) error {
 	if len(itemsToDelete) > 0 {
 		log.Infof("deleting: %v", itemsToDelete)
 
-		if _, err := api.Modify().Delete(itemsToDelete...).Do(ctx); err != nil {
-			return errors.Wrapf(err, "articles.Delete(%s)", itemsToDelete)
+		results := pocket.RunBatch(ctx, config.AccessToken(), &pocket.BatchAction{Action: pocket.BatchDelete, ItemIDs: itemsToDelete})
+		forgetArticles(ctx, articleCache, pocket.RemovedItems(results)...)
+
+		var failed []string
+		for _, result := range results {
+			if !result.OK {
+				failed = append(failed, result.ItemID)
+			}
+		}
+
+		if len(failed) > 0 {
+			return errors.Errorf("articles.Delete(%s) failed", failed)
 		}
 	}
 
@@ -9,11 +9,12 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/whitekid/getpocket"
-	"github.com/whitekid/goxp/fx"
 	"github.com/whitekid/goxp/log"
 	"golang.org/x/exp/maps"
 
+	pocket "pocket-pick"
 	"pocket-pick/config"
+	"pocket-pick/pkg/cache"
 )
 
 func init() {
@@ -28,6 +29,27 @@ func init() {
 
 func deleteArticle(ctx context.Context, idOrURLs ...string) error {
 	api := getpocket.New(config.ConsumerKey(), config.AccessToken())
+	trash, err := pocket.OpenTrash(ctx)
+	if err != nil {
+		return errors.Wrap(err, "open trash")
+	}
+
+	if trash.Enabled() {
+		if _, err := trash.Flush(ctx, config.AccessToken()); err != nil && err != cache.ErrLocked {
+			log.Errorf("flush trash failed: %s", err)
+		}
+	}
+
+	// articles of user to queue deletion by id with its detail, fetched on first delete by id
+	var all map[string]*getpocket.Article
+
+	var removed []string
+	defer func() {
+		if len(removed) > 0 {
+			forgetArticles(ctx, openArticleCache(ctx), removed...)
+		}
+	}()
+
 	for _, idOrURL := range idOrURLs {
 		// delete by url
 		if strings.HasPrefix(idOrURL, "http://") || strings.HasPrefix(idOrURL, "https://") {
@@ -40,23 +62,61 @@ func deleteArticle(ctx context.Context, idOrURLs ...string) error {
 				return fmt.Errorf("not found: %s", idOrURL)
 			}
 
-			ids := fx.Map(maps.Values(items), func(e *getpocket.Article) string { return e.ItemID })
-			log.Infof("deleting %s", ids)
-			if _, err := api.Modify().Delete(ids...).Do(ctx); err != nil {
-				return errors.Wrapf(err, "articles.Delete(%s)", ids)
+			if err := removeArticles(ctx, api, trash, items, maps.Keys(items)...); err != nil {
+				return err
 			}
+			removed = append(removed, maps.Keys(items)...)
 		} else {
 			// delete by id
 			if _, err := strconv.Atoi(idOrURL); err != nil {
 				return fmt.Errorf("%s is not valid id", idOrURL)
 			}
 
-			log.Infof("deleting item %s", idOrURL)
-			if _, err := api.Modify().Delete(idOrURL).Do(ctx); err != nil {
-				return errors.Wrapf(err, "articles.Delete(%s)", idOrURL)
+			items := map[string]*getpocket.Article{}
+			if trash.Enabled() {
+				if all == nil {
+					var err error
+					if all, err = api.Articles().Get().State(getpocket.StateAll).Do(ctx); err != nil {
+						return errors.Wrap(err, "articles.Get()")
+					}
+				}
+
+				// article without detail can not be restored by undo
+				article, ok := all[idOrURL]
+				if !ok {
+					return fmt.Errorf("not found: %s", idOrURL)
+				}
+				items[idOrURL] = article
+			}
+
+			if err := removeArticles(ctx, api, trash, items, idOrURL); err != nil {
+				return err
 			}
+			removed = append(removed, idOrURL)
 		}
 	}
 
 	return nil
 }
+
+// removeArticles delete articles or queue them to trash if soft delete enabled
+func removeArticles(ctx context.Context, api *getpocket.Client, trash *pocket.Trash, items map[string]*getpocket.Article, ids ...string) error {
+	if trash.Enabled() {
+		entries, err := trash.Put(ctx, config.AccessToken(), items, ids...)
+		if err != nil {
+			return errors.Wrapf(err, "trash.Put(%s)", ids)
+		}
+
+		for _, entry := range entries {
+			log.Infof("item %s will be deleted at %s, run `undo %s` to restore", entry.ItemID, entry.DeleteAt, entry.ItemID)
+		}
+		return nil
+	}
+
+	log.Infof("deleting %s", ids)
+	if _, err := api.Modify().Delete(ids...).Do(ctx); err != nil {
+		return errors.Wrapf(err, "articles.Delete(%s)", ids)
+	}
+
+	return nil
+}
@@ -8,8 +8,15 @@ import (
 )
 
 var rootCmd = &cobra.Command{
-	Use:  "pocket-pick",
-	RunE: func(cmd *cobra.Command, args []string) error { return pocket.New(cmd.Context()).Serve(cmd.Context()) },
+	Use: "pocket-pick",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := pocket.New(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		return s.Serve(cmd.Context())
+	},
 }
 
 func init() {
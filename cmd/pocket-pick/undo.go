@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/whitekid/goxp/log"
+
+	pocket "pocket-pick"
+	"pocket-pick/config"
+	"pocket-pick/pkg/cache"
+)
+
+func init() {
+	rootCmd.AddCommand(&cobra.Command{
+		Use:          "undo [item_id...]",
+		Long:         "restore deleted articles from trash, list trash if no item given",
+		SilenceUsage: true,
+		RunE:         func(cmd *cobra.Command, args []string) error { return undoDelete(cmd.Context(), args...) },
+	})
+}
+
+func undoDelete(ctx context.Context, itemIDs ...string) error {
+	trash, err := pocket.OpenTrash(ctx)
+	if err != nil {
+		return errors.Wrap(err, "open trash")
+	}
+
+	if len(itemIDs) == 0 {
+		// execute expired deletions before list, unless the server is flushing them
+		if _, err := trash.Flush(ctx, config.AccessToken()); err != nil && err != cache.ErrLocked {
+			return errors.Wrap(err, "trash.Flush()")
+		}
+
+		entries, err := trash.List(ctx, config.AccessToken())
+		if err != nil {
+			return errors.Wrap(err, "trash.List()")
+		}
+
+		for _, entry := range entries {
+			state := "pending"
+			if entry.Deleted {
+				state = "deleted"
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\n", entry.ItemID, state, entry.DeleteAt.Format(time.RFC3339), entry.URL)
+		}
+		return nil
+	}
+
+	restored := 0
+	defer func() {
+		if restored > 0 {
+			resyncArticles(ctx, openArticleCache(ctx))
+		}
+	}()
+
+	for _, itemID := range itemIDs {
+		if _, err := trash.Undo(ctx, config.AccessToken(), itemID); err != nil {
+			return errors.Wrapf(err, "trash.Undo(%s)", itemID)
+		}
+		log.Infof("item %s restored", itemID)
+		restored++
+	}
+
+	return nil
+}
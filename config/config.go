@@ -1,6 +1,8 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -17,6 +19,29 @@ const (
 	keyAccessToken   = "access_token"
 	keyCookieTimeout = "cookie_timeout"
 	keyCacheTimeout  = "favorite_cache_timeout"
+	keyCacheMaxAge   = "favorite_cache_max_age"
+	keyFullSync      = "favorite_full_sync_interval"
+	keyLegacyDelete  = "legacy_delete"
+	keyDeleteGrace   = "delete_grace_period"
+	keyTrashRetain   = "trash_retention"
+	keyTrashFile     = "trash_file"
+	keyPocketTimeout = "pocket_timeout"
+	keyBreakerFails  = "breaker_failures"
+	keyBreakerWait   = "breaker_timeout"
+	keyCacheBackend  = "cache_backend"
+	keyCachePath     = "cache_path"
+	keyCacheMaxSize  = "cache_max_size"
+	keyCacheShards   = "cache_shards"
+	keyCacheL1TTL    = "cache_l1_ttl"
+	keyRedisURL      = "redis_url"
+	keyCacheCodec    = "cache_codec"
+	keyCacheCompress = "cache_compress_threshold"
+	keyCacheDict     = "cache_zstd_dict"
+	keyCacheFormat   = "cache_serializer"
+	keyCacheEncrypt  = "cache_encrypt"
+	keyCacheEncKeys  = "cache_encryption_keys"
+	keyAdminToken    = "admin_token"
+	keyMemcache      = "memcache_servers"
 )
 
 var configs = map[string][]flags.Flag{
@@ -27,7 +52,30 @@ var configs = map[string][]flags.Flag{
 		{keyConsumerKey, "k", "", "getpocket consumer key"},
 		{keyAccessToken, "a", "", "getpocket access token"},
 		{keyCookieTimeout, "c", time.Hour * 24 * 30 * 12, "cookie timeout"},
-		{keyCacheTimeout, "", time.Hour, "timeout for cache favorite items"},
+		{keyCacheTimeout, "", time.Hour, "timeout for cache favorite items, refresh in background after timeout"},
+		{keyCacheMaxAge, "", time.Hour * 24, "max age of cached favorite items, served while refreshing"},
+		{keyFullSync, "", time.Hour * 24 * 7, "interval to fetch all favorite items instead of changes since last sync"},
+		{keyLegacyDelete, "", true, "allow delete article with GET /article/:item_id"},
+		{keyDeleteGrace, "", time.Duration(0), "wait before delete article from pocket, 0 to delete immediately"},
+		{keyTrashRetain, "", time.Hour * 24 * 7, "keep deleted articles in trash to restore them"},
+		{keyTrashFile, "", "", "trash file for command line and server without redis, default to $HOME/.config/pocket-pick/trash.json"},
+		{keyPocketTimeout, "", time.Second * 10, "timeout for getpocket api call"},
+		{keyBreakerFails, "", 5, "consecutive getpocket api failures to open circuit breaker"},
+		{keyBreakerWait, "", time.Second * 30, "wait before probe getpocket api when circuit breaker is open"},
+		{keyCacheBackend, "", "", "cache backend: bigcache, redis, tiered, memcache, disk or none; disk if cache_path set, otherwise bigcache"},
+		{keyCachePath, "", "", "cache file for disk cache to keep cache across restarts"},
+		{keyCacheMaxSize, "", 512, "max size of bigcache and disk cache in MB, 0 for unlimited"},
+		{keyCacheShards, "", 1024, "number of bigcache shards, must be power of two"},
+		{keyCacheL1TTL, "", time.Minute, "max age of in-process copies of tiered cache"},
+		{keyRedisURL, "", "", "redis url for redis and tiered cache, e.g. rediss://:password@localhost:6379/0, redis+sentinel://localhost:26379/0?master=mymaster or redis+cluster://localhost:7000?addr=localhost:7001"},
+		{keyMemcache, "", "", "comma separated memcached servers for memcache cache, e.g. localhost:11211"},
+		{keyCacheCodec, "", "zstd", "compression of cached values: none, zstd, gzip or s2"},
+		{keyCacheCompress, "", 1024, "do not compress cached values smaller than this bytes"},
+		{keyCacheDict, "", "", "zstd dictionary file for cached values, keep it while cache is alive"},
+		{keyCacheFormat, "", "json", "serializer of cached values: json, gob or msgpack"},
+		{keyCacheEncrypt, "", false, "encrypt cached values with keys derived for each user"},
+		{keyCacheEncKeys, "", "", "comma separated id:secret to encrypt cached values, first one encrypts new values; secret as key id 1 if empty"},
+		{keyAdminToken, "", "", "bearer token for admin api, admin api is disabled if empty"},
 	},
 }
 
@@ -47,4 +95,48 @@ func SecretKey() string                   { return viper.GetString(keySecretKey)
 func ConsumerKey() string                 { return cryptox.MustDecrypt(SecretKey(), viper.GetString(keyConsumerKey)) }
 func AccessToken() string                 { return cryptox.MustDecrypt(SecretKey(), viper.GetString(keyAccessToken)) }
 func CacheEvictionTimeout() time.Duration { return viper.GetDuration(keyCacheTimeout) }
+func CacheMaxAge() time.Duration          { return viper.GetDuration(keyCacheMaxAge) }
+func FullSyncInterval() time.Duration     { return viper.GetDuration(keyFullSync) }
 func CookieTimeout() time.Duration        { return viper.GetDuration(keyCookieTimeout) }
+func LegacyDelete() bool                  { return viper.GetBool(keyLegacyDelete) }
+func DeleteGracePeriod() time.Duration    { return viper.GetDuration(keyDeleteGrace) }
+func TrashRetention() time.Duration       { return viper.GetDuration(keyTrashRetain) }
+func PocketTimeout() time.Duration        { return viper.GetDuration(keyPocketTimeout) }
+func BreakerFailures() int                { return viper.GetInt(keyBreakerFails) }
+func BreakerTimeout() time.Duration       { return viper.GetDuration(keyBreakerWait) }
+func CachePath() string                   { return viper.GetString(keyCachePath) }
+func CacheMaxSize() int                   { return viper.GetInt(keyCacheMaxSize) }
+func CacheShards() int                    { return viper.GetInt(keyCacheShards) }
+func CacheL1TTL() time.Duration           { return viper.GetDuration(keyCacheL1TTL) }
+func RedisURL() string                    { return viper.GetString(keyRedisURL) }
+func MemcacheServers() string             { return viper.GetString(keyMemcache) }
+func CacheCodec() string                  { return viper.GetString(keyCacheCodec) }
+func CacheCompressThreshold() int         { return viper.GetInt(keyCacheCompress) }
+func CacheZstdDict() string               { return viper.GetString(keyCacheDict) }
+func CacheSerializer() string             { return viper.GetString(keyCacheFormat) }
+func CacheEncrypt() bool                  { return viper.GetBool(keyCacheEncrypt) }
+func CacheEncryptionKeys() string         { return viper.GetString(keyCacheEncKeys) }
+func AdminToken() string                  { return viper.GetString(keyAdminToken) }
+
+func CacheBackend() string {
+	if backend := viper.GetString(keyCacheBackend); backend != "" {
+		return backend
+	}
+
+	if CachePath() != "" {
+		return "disk"
+	}
+	return "bigcache"
+}
+
+func TrashFile() string {
+	if file := viper.GetString(keyTrashFile); file != "" {
+		return file
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "pocket-pick", "trash.json")
+}
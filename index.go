@@ -0,0 +1,92 @@
+package pocket
+
+import (
+	"math/rand"
+	"net/url"
+	"strings"
+
+	"github.com/whitekid/getpocket"
+)
+
+// articleIndex favorite articles of user decoded in memory, so picks do not decode cached articles again
+// it is rebuilt when version of cached articles changed
+type articleIndex struct {
+	version  string
+	articles []*getpocket.Article
+	byTag    map[string][]int // tag -> index of articles
+	byDomain map[string][]int // domain -> index of articles
+}
+
+func newArticleIndex(version string, articles map[string]*getpocket.Article) *articleIndex {
+	idx := &articleIndex{
+		version:  version,
+		articles: make([]*getpocket.Article, 0, len(articles)),
+		byTag:    make(map[string][]int),
+		byDomain: make(map[string][]int),
+	}
+
+	for _, article := range articles {
+		i := len(idx.articles)
+		idx.articles = append(idx.articles, article)
+
+		for tag := range article.Tags {
+			idx.byTag[tag] = append(idx.byTag[tag], i)
+		}
+
+		if domain := articleDomain(article); domain != "" {
+			idx.byDomain[domain] = append(idx.byDomain[domain], i)
+		}
+	}
+
+	return idx
+}
+
+// articleDomain return host of article url
+func articleDomain(article *getpocket.Article) string {
+	u, err := url.Parse(articleURL(article))
+	if err != nil {
+		return ""
+	}
+
+	return normalizeDomain(u.Hostname())
+}
+
+// normalizeDomain return lower cased domain without www.
+func normalizeDomain(domain string) string {
+	return strings.TrimPrefix(strings.ToLower(domain), "www.")
+}
+
+// pick return random article which has tag and domain, empty for any
+// return nil if no article matched
+func (idx *articleIndex) pick(tag string, domain string) *getpocket.Article {
+	domain = normalizeDomain(domain)
+
+	switch {
+	case tag == "" && domain == "":
+		if len(idx.articles) == 0 {
+			return nil
+		}
+		return idx.articles[rand.Intn(len(idx.articles))]
+
+	case domain == "":
+		return idx.pickFrom(idx.byTag[tag])
+
+	case tag == "":
+		return idx.pickFrom(idx.byDomain[domain])
+	}
+
+	var matched []int
+	for _, i := range idx.byTag[tag] {
+		if articleDomain(idx.articles[i]) == domain {
+			matched = append(matched, i)
+		}
+	}
+	return idx.pickFrom(matched)
+}
+
+func (idx *articleIndex) pickFrom(indexes []int) *getpocket.Article {
+	if len(indexes) == 0 {
+		return nil
+	}
+	return idx.articles[indexes[rand.Intn(len(indexes))]]
+}
@@ -0,0 +1,100 @@
+package pocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/whitekid/getpocket"
+	"github.com/whitekid/goxp/fx"
+)
+
+func TestArticleIndex(t *testing.T) {
+	idx := newArticleIndex("v1", map[string]*getpocket.Article{
+		"1": {ItemID: "1", ResolvedURL: "https://www.Example.com/1", Tags: map[string]getpocket.Tag{"go": {}}},
+		"2": {ItemID: "2", GivenURL: "https://example.com/2", Tags: map[string]getpocket.Tag{"rust": {}}},
+		"3": {ItemID: "3", ResolvedURL: "https://go.dev/3", Tags: map[string]getpocket.Tag{"go": {}}},
+	})
+
+	type args struct {
+		tag    string
+		domain string
+	}
+	tests := [...]struct {
+		name string
+		args args
+		want []string
+	}{
+		{"any", args{"", ""}, []string{"1", "2", "3"}},
+		{"tag", args{"go", ""}, []string{"1", "3"}},
+		{"domain", args{"", "www.example.com"}, []string{"1", "2"}},
+		{"tag and domain", args{"go", "example.com"}, []string{"1"}},
+		{"not matched", args{"python", ""}, nil},
+		{"tag and domain not matched", args{"rust", "go.dev"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				got := idx.pick(tt.args.tag, tt.args.domain)
+				if tt.want == nil {
+					require.Nil(t, got)
+					continue
+				}
+				require.Contains(t, tt.want, got.ItemID)
+			}
+		})
+	}
+}
+
+func sampleFavorites(n int) map[string]*getpocket.Article {
+	articles := make(map[string]*getpocket.Article, n)
+	for i := 0; i < n; i++ {
+		itemID := fmt.Sprintf("%d", 1000000000+i)
+		articles[itemID] = &getpocket.Article{
+			ItemID:        itemID,
+			ResolvedID:    itemID,
+			ResolvedURL:   fmt.Sprintf("https://example%d.com/articles/%d", i%100, i),
+			ResolvedTitle: fmt.Sprintf("article %d", i),
+			Excerpt:       "lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore",
+			Favorite:      "1",
+			Tags:          map[string]getpocket.Tag{fmt.Sprintf("tag%d", i%20): {}},
+		}
+	}
+	return articles
+}
+
+func BenchmarkPick(b *testing.B) {
+	for _, n := range []int{10000, 50000} {
+		articles := sampleFavorites(n)
+		data, err := json.Marshal(articles)
+		require.NoError(b, err)
+
+		b.Run(fmt.Sprintf("decode-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var decoded map[string]*getpocket.Article
+				require.NoError(b, json.Unmarshal(data, &decoded))
+				fx.SampleMap(decoded)
+			}
+		})
+
+		idx := newArticleIndex("v1", articles)
+		b.Run(fmt.Sprintf("index-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				idx.pick("", "")
+			}
+		})
+
+		b.Run(fmt.Sprintf("index-tag-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				idx.pick("tag1", "")
+			}
+		})
+
+		b.Run(fmt.Sprintf("build-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				newArticleIndex("v1", articles)
+			}
+		})
+	}
+}
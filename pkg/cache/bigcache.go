@@ -2,7 +2,9 @@ package cache
 
 import (
 	"context"
-	"fmt"
+	"encoding/binary"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/allegro/bigcache/v3"
@@ -10,82 +12,196 @@ import (
 )
 
 func NewBigCache(ctx context.Context) Interface {
-	config := bigcache.DefaultConfig(time.Hour)
+	cache, _ := NewBigCacheWithConfig(ctx, 0, 0)
+	return cache
+}
+
+// NewBigCacheWithConfig return bigcache with number of shards and max size in MB
+// 0 for default shards and unlimited size; shards must be power of two
+func NewBigCacheWithConfig(ctx context.Context, shards int, maxSize int) (Interface, error) {
+	// entries expire by WithExpire, life window is upper bound of expiration
+	config := bigcache.DefaultConfig(time.Hour * 24)
 	config.CleanWindow = time.Minute
-	cache, _ := bigcache.New(ctx, config)
-	return &bigCacheImpl{
-		cache: cache,
+	if shards != 0 {
+		config.Shards = shards
+	}
+	config.HardMaxCacheSize = maxSize
+
+	b := &bigCacheImpl{}
+	config.OnRemoveWithReason = func(key string, entry []byte, reason bigcache.RemoveReason) {
+		if reason == bigcache.NoSpace {
+			b.evictions.Add(1)
+		}
+	}
+
+	cache, err := bigcache.New(ctx, config)
+	if err != nil {
+		return nil, err
 	}
+	b.cache = cache
+
+	return b, nil
 }
 
+// bigcache entry header: version, codec, expire at in unix nano; 0 for no expiration
+// values are stored as given, compress them with NewCodec
+const (
+	bigCacheVersion    = 1
+	bigCacheHeaderSize = 10
+
+	bigCacheCodecNone = 0
+)
+
 type bigCacheImpl struct {
-	cache *bigcache.BigCache
+	cache     *bigcache.BigCache
+	loads     loadGroup
+	evictions atomic.Int64 // entries removed for space
 }
 
 var _ Interface = (*bigCacheImpl)(nil)
 
+func (b *bigCacheImpl) backendStats() map[string]int64 {
+	stats := b.cache.Stats()
+	return map[string]int64{
+		"entries":    int64(b.cache.Len()),
+		"capacity":   int64(b.cache.Capacity()),
+		"hits":       stats.Hits,
+		"misses":     stats.Misses,
+		"collisions": stats.Collisions,
+		"evictions":  b.evictions.Load(),
+	}
+}
+
 func (b *bigCacheImpl) Set(ctx context.Context, key string, value []byte, opts ...setOption) error {
 	option := applySetOptions(opts)
 
-	value, err := zstdCompress(value)
+	var expireAt int64
+	if option.expire != 0 {
+		expireAt = time.Now().Add(option.expire).UnixNano()
+	}
+
+	entry := make([]byte, bigCacheHeaderSize+len(value))
+	entry[0] = bigCacheVersion
+	entry[1] = bigCacheCodecNone
+	binary.BigEndian.PutUint64(entry[2:], uint64(expireAt))
+	copy(entry[bigCacheHeaderSize:], value)
+
+	return b.cache.Set(key, entry)
+}
+
+// entry return entry of the key without header, and its expiration; zero time for no expiration
+// return ErrNotExists if key not exists or expired
+func (b *bigCacheImpl) entry(key string) ([]byte, byte, time.Time, error) {
+	entry, err := b.cache.Get(key)
 	if err != nil {
-		return err
+		if err == bigcache.ErrEntryNotFound {
+			return nil, 0, time.Time{}, ErrNotExists
+		}
+		return nil, 0, time.Time{}, err
 	}
 
-	if err := b.cache.Set(key, value); err != nil {
-		return err
+	if len(entry) < bigCacheHeaderSize || entry[0] != bigCacheVersion {
+		return nil, 0, time.Time{}, errors.Errorf("invalid entry: %s", key)
 	}
 
-	expireKey := fmt.Sprintf("%s/expire", key)
-	if option.expire != 0 {
-		timez := time.Now().Add(option.expire).Format(time.RFC3339)
-		b.cache.Set(expireKey, []byte(timez))
-	} else {
-		b.cache.Delete(expireKey)
+	var expireAt time.Time
+	if nsec := int64(binary.BigEndian.Uint64(entry[2:])); nsec != 0 {
+		expireAt = time.Unix(0, nsec)
+		if expireAt.Before(time.Now()) {
+			b.cache.Delete(key)
+			return nil, 0, time.Time{}, ErrNotExists
+		}
 	}
 
-	return nil
+	return entry[bigCacheHeaderSize:], entry[1], expireAt, nil
 }
 
 func (b *bigCacheImpl) Get(ctx context.Context, key string) ([]byte, error) {
-	data, err := b.cache.Get(key)
+	data, codec, _, err := b.entry(key)
 	if err != nil {
-		if err == bigcache.ErrEntryNotFound {
-			return nil, ErrNotExists
-		}
 		return nil, err
 	}
 
-	data, err = zstdDecompress(data)
-	if err != nil {
-		return nil, errors.Wrapf(err, "fail to decompress")
+	if codec != bigCacheCodecNone {
+		return nil, errors.Errorf("unknown codec: %d", codec)
 	}
 
-	expireb, err := b.cache.Get(fmt.Sprintf("%s/expire", key))
-	if err != nil {
-		return data, nil
+	return data, nil
+}
+
+func (b *bigCacheImpl) Has(ctx context.Context, key string) bool {
+	_, _, _, err := b.entry(key)
+	return err == nil
+}
+
+func (b *bigCacheImpl) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		b.cache.Delete(key)
 	}
+	return nil
+}
 
-	expire, err := time.Parse(time.RFC3339, string(expireb))
+func (b *bigCacheImpl) TTL(ctx context.Context, key string) (time.Duration, error) {
+	_, _, expireAt, err := b.entry(key)
 	if err != nil {
-		return data, nil
+		return 0, err
+	}
+
+	if expireAt.IsZero() {
+		return 0, nil
 	}
+	return time.Until(expireAt), nil
+}
 
-	if expire.Before(time.Now()) {
-		go func() {
-			<-time.After(time.Second)
+func (b *bigCacheImpl) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	now := time.Now().UnixNano()
 
-			b.cache.Delete(key)
-			b.cache.Delete(fmt.Sprintf("%s/expire", key))
-		}()
+	it := b.cache.Iterator()
+	for it.SetNext() {
+		entry, err := it.Value()
+		if err != nil {
+			return nil, err
+		}
 
-		return nil, ErrNotExists
+		if !strings.HasPrefix(entry.Key(), prefix) {
+			continue
+		}
+
+		// iterator value is a copy, so check expiration without another lookup
+		if value := entry.Value(); len(value) >= bigCacheHeaderSize {
+			if expireAt := int64(binary.BigEndian.Uint64(value[2:])); expireAt != 0 && expireAt < now {
+				continue
+			}
+		}
+
+		keys = append(keys, entry.Key())
 	}
 
-	return data, nil
+	return keys, nil
 }
 
-func (b *bigCacheImpl) Has(ctx context.Context, key string) bool {
-	_, err := b.cache.Get(key)
-	return err == nil
+func (b *bigCacheImpl) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := b.Get(ctx, key)
+		if err != nil && err != ErrNotExists {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func (b *bigCacheImpl) MSet(ctx context.Context, values map[string][]byte, opts ...setOption) error {
+	for key, value := range values {
+		if err := b.Set(ctx, key, value, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *bigCacheImpl) GetOrLoad(ctx context.Context, key string, loader LoaderFunc, opts ...setOption) ([]byte, error) {
+	return b.loads.getOrLoad(ctx, b, key, loader, opts)
 }
@@ -30,3 +30,22 @@ func TestCache(t *testing.T) {
 		require.NotEqual(t, []byte("world"), value)
 	}
 }
+
+func TestBigCacheExpire(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache := NewBigCache(ctx)
+	require.NoError(t, cache.Set(ctx, "hello", []byte("world"), WithExpire(100*time.Millisecond)))
+	require.NoError(t, cache.Set(ctx, "hello2", []byte("world"), WithExpire(100*time.Millisecond)))
+	require.True(t, cache.Has(ctx, "hello"))
+
+	time.Sleep(150 * time.Millisecond)
+	require.False(t, cache.Has(ctx, "hello"), "Has should honor expiration")
+	_, err := cache.Get(ctx, "hello")
+	require.ErrorIs(t, err, ErrNotExists)
+
+	keys, err := cache.Keys(ctx, "hello")
+	require.NoError(t, err)
+	require.Empty(t, keys, "expired entries should not be listed")
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"errors"
+	"time"
 )
 
 type Interface interface {
@@ -13,8 +14,32 @@ type Interface interface {
 
 	// return true if key exists
 	Has(ctx context.Context, key string) bool
+
+	// remove keys, not existing keys are ignored
+	Delete(ctx context.Context, keys ...string) error
+
+	// return remaining time to expire, 0 if key has no expiration
+	// return ErrNotExists if key not exists
+	TTL(ctx context.Context, key string) (time.Duration, error)
+
+	// return keys which start with prefix, ErrNotSupported if backend can not list keys
+	Keys(ctx context.Context, prefix string) ([]string, error)
+
+	// return values in the order of keys, nil for not existing keys
+	MGet(ctx context.Context, keys ...string) ([][]byte, error)
+
+	// set values at once with the same options
+	MSet(ctx context.Context, values map[string][]byte, opts ...setOption) error
+
+	// return cached value, or load value with loader and set it to cache if key not exists
+	// concurrent calls for the same key wait for the single loader
+	GetOrLoad(ctx context.Context, key string, loader LoaderFunc, opts ...setOption) ([]byte, error)
 }
 
+// LoaderFunc load value for the key which not exists in cache
+type LoaderFunc func(ctx context.Context) ([]byte, error)
+
 var (
-	ErrNotExists = errors.New("not exists")
+	ErrNotExists    = errors.New("not exists")
+	ErrNotSupported = errors.New("not supported")
 )
@@ -2,7 +2,12 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/alicebob/miniredis/v2"
 	"github.com/redis/go-redis/v9"
@@ -27,6 +32,8 @@ func TestCacher(t *testing.T) {
 	}{
 		{"bigcache", args{NewBigCache(context.Background())}},
 		{"redis", args{NewRedis(r)}},
+		{"disk", args{newTestDisk(t, 0)}},
+		{"tiered", args{newTestTiered(t, newTestRedis(t))}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -46,3 +53,113 @@ func TestCacher(t *testing.T) {
 		})
 	}
 }
+
+func TestGetOrLoad(t *testing.T) {
+	s := miniredis.RunT(t)
+	r := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer s.Close()
+
+	type args struct {
+		cacher Interface
+	}
+	tests := [...]struct {
+		name string
+		args args
+	}{
+		{"bigcache", args{NewBigCache(context.Background())}},
+		{"redis", args{NewRedis(r)}},
+		{"disk", args{newTestDisk(t, 0)}},
+		{"tiered", args{newTestTiered(t, newTestRedis(t))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "sample-key"
+			value := []byte("sample-value")
+
+			var loads atomic.Int32
+			loader := func(ctx context.Context) ([]byte, error) {
+				loads.Add(1)
+				time.Sleep(100 * time.Millisecond)
+				return value, nil
+			}
+
+			cacher := tt.args.cacher
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					got, err := cacher.GetOrLoad(ctx, key, loader, WithExpire(time.Minute))
+					require.NoError(t, err)
+					require.Equal(t, value, got)
+				}()
+			}
+			wg.Wait()
+			require.Equal(t, int32(1), loads.Load(), "concurrent loads should be coalesced")
+
+			got, err := cacher.GetOrLoad(ctx, key, loader)
+			require.NoError(t, err)
+			require.Equal(t, value, got)
+			require.Equal(t, int32(1), loads.Load(), "should load from cache")
+
+			// loader error is not cached
+			_, err = cacher.GetOrLoad(ctx, "error-key", func(ctx context.Context) ([]byte, error) { return nil, errors.New("load failed") })
+			require.Error(t, err)
+			require.False(t, cacher.Has(ctx, "error-key"))
+		})
+	}
+}
+
+func TestGetOrLoadSoftExpire(t *testing.T) {
+	s := miniredis.RunT(t)
+	r := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer s.Close()
+
+	type args struct {
+		cacher Interface
+	}
+	tests := [...]struct {
+		name string
+		args args
+	}{
+		{"bigcache", args{NewBigCache(context.Background())}},
+		{"redis", args{NewRedis(r)}},
+		{"disk", args{newTestDisk(t, 0)}},
+		{"tiered", args{newTestTiered(t, newTestRedis(t))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "sample-key"
+
+			var loads atomic.Int32
+			loader := func(ctx context.Context) ([]byte, error) {
+				return []byte(fmt.Sprintf("value-%d", loads.Add(1))), nil
+			}
+			opts := []setOption{WithExpire(time.Minute), WithSoftExpire(100 * time.Millisecond)}
+
+			cacher := tt.args.cacher
+			got, err := cacher.GetOrLoad(ctx, key, loader, opts...)
+			require.NoError(t, err)
+			require.Equal(t, []byte("value-1"), got)
+
+			got, err = cacher.GetOrLoad(ctx, key, loader, opts...)
+			require.NoError(t, err)
+			require.Equal(t, []byte("value-1"), got, "fresh value")
+
+			// stale value is served and refreshed in background
+			time.Sleep(150 * time.Millisecond)
+			got, err = cacher.GetOrLoad(ctx, key, loader, opts...)
+			require.NoError(t, err)
+			require.Equal(t, []byte("value-1"), got, "stale value")
+
+			require.Eventually(t, func() bool {
+				got, err := cacher.GetOrLoad(ctx, key, loader, opts...)
+				return err == nil && string(got) == "value-2"
+			}, time.Second, 10*time.Millisecond)
+			require.Equal(t, int32(2), loads.Load())
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/pkg/errors"
+)
+
+// Format compression format of cached value
+type Format byte
+
+const (
+	FormatNone Format = iota
+	FormatZstd
+	FormatGzip
+	FormatS2
+)
+
+var formatNames = map[Format]string{
+	FormatNone: "none",
+	FormatZstd: "zstd",
+	FormatGzip: "gzip",
+	FormatS2:   "s2",
+}
+
+func (f Format) String() string { return formatNames[f] }
+
+// ParseFormat return format by name
+func ParseFormat(name string) (Format, error) {
+	for format, formatName := range formatNames {
+		if formatName == name {
+			return format, nil
+		}
+	}
+	return FormatNone, errors.Errorf("unknown format: %s", name)
+}
+
+// codecMagic mark encoded value; 0xc0 never appears in utf-8 text, so it is not confused with legacy json values
+const codecMagic = 0xc0
+
+// zstd frame magic number of legacy compressed values
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// NewCodec return cache which compress values with format before store them to c
+// values smaller than threshold are stored without compression
+// values are prefixed with format, so values written with any format or legacy raw/zstd values are readable
+func NewCodec(c Interface, format Format, threshold int, opts ...CodecOption) (Interface, error) {
+	codec := &codecCacheImpl{
+		cache:     c,
+		format:    format,
+		threshold: threshold,
+		zstd:      defaultZstd(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(codec); err != nil {
+			return nil, err
+		}
+	}
+
+	return codec, nil
+}
+
+// CodecOption option of NewCodec
+type CodecOption func(c *codecCacheImpl) error
+
+// WithZstdDictionary compress zstd values with dictionary trained by TrainZstdDictionary
+// values compressed with dictionary can not be read without it
+func WithZstdDictionary(dictionary []byte) CodecOption {
+	return func(c *codecCacheImpl) error {
+		z, err := newZstdCodec(dictionary)
+		if err != nil {
+			return errors.Wrap(err, "invalid zstd dictionary")
+		}
+
+		c.zstd = z
+		return nil
+	}
+}
+
+type codecCacheImpl struct {
+	cache     Interface
+	format    Format
+	threshold int
+	zstd      *zstdCodec
+	loads     loadGroup
+}
+
+var _ Interface = (*codecCacheImpl)(nil)
+
+// encode return value compressed with format and prefixed with format header
+func (c *codecCacheImpl) encode(value []byte) ([]byte, error) {
+	format := c.format
+	if len(value) < c.threshold {
+		format = FormatNone
+	}
+
+	var data []byte
+	var err error
+	switch format {
+	case FormatNone:
+		data = value
+	case FormatZstd:
+		data = c.zstd.compress(value)
+	case FormatGzip:
+		data, err = gzipCompress(value)
+	case FormatS2:
+		data = s2.Encode(nil, value)
+	default:
+		return nil, errors.Errorf("unknown format: %d", format)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to compress with %s", format)
+	}
+
+	return append([]byte{codecMagic, byte(format)}, data...), nil
+}
+
+// decode return value decoded by its header, legacy values without header are decoded as raw or zstd
+func (c *codecCacheImpl) decode(data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != codecMagic {
+		if bytes.HasPrefix(data, zstdMagic) {
+			return c.zstd.decompress(data)
+		}
+		return data, nil
+	}
+
+	var value []byte
+	var err error
+	switch format := Format(data[1]); format {
+	case FormatNone:
+		value = data[2:]
+	case FormatZstd:
+		value, err = c.zstd.decompress(data[2:])
+	case FormatGzip:
+		value, err = gzipDecompress(data[2:])
+	case FormatS2:
+		value, err = s2.Decode(nil, data[2:])
+	default:
+		return nil, errors.Errorf("unknown format: %d", format)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to decompress")
+	}
+
+	// nil is not exists for MGet
+	if value == nil {
+		value = []byte{}
+	}
+
+	return value, nil
+}
+
+func (c *codecCacheImpl) Set(ctx context.Context, key string, value []byte, opts ...setOption) error {
+	data, err := c.encode(value)
+	if err != nil {
+		return err
+	}
+
+	return c.cache.Set(ctx, key, data, opts...)
+}
+
+func (c *codecCacheImpl) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.decode(data)
+}
+
+func (c *codecCacheImpl) Has(ctx context.Context, key string) bool { return c.cache.Has(ctx, key) }
+
+func (c *codecCacheImpl) Delete(ctx context.Context, keys ...string) error {
+	return c.cache.Delete(ctx, keys...)
+}
+
+func (c *codecCacheImpl) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return c.cache.TTL(ctx, key)
+}
+
+func (c *codecCacheImpl) Keys(ctx context.Context, prefix string) ([]string, error) {
+	return c.cache.Keys(ctx, prefix)
+}
+
+func (c *codecCacheImpl) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	values, err := c.cache.MGet(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, data := range values {
+		if data == nil {
+			continue
+		}
+
+		if values[i], err = c.decode(data); err != nil {
+			return nil, errors.Wrapf(err, "key: %s", keys[i])
+		}
+	}
+	return values, nil
+}
+
+func (c *codecCacheImpl) MSet(ctx context.Context, values map[string][]byte, opts ...setOption) error {
+	encoded := make(map[string][]byte, len(values))
+	for key, value := range values {
+		data, err := c.encode(value)
+		if err != nil {
+			return err
+		}
+		encoded[key] = data
+	}
+
+	return c.cache.MSet(ctx, encoded, opts...)
+}
+
+func (c *codecCacheImpl) GetOrLoad(ctx context.Context, key string, loader LoaderFunc, opts ...setOption) ([]byte, error) {
+	return c.loads.getOrLoad(ctx, c, key, loader, opts)
+}
+
+// gzipCompress compress with gzip
+func gzipCompress(src []byte) ([]byte, error) {
+	out := bytes.NewBuffer(nil)
+	w := gzip.NewWriter(out)
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// gzipDecompress decompress with gzip
+func gzipDecompress(src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
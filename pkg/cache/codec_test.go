@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec(t *testing.T) {
+	value := bytes.Repeat([]byte(`{"item_id":"1234","resolved_title":"hello world"}`), 100)
+
+	type args struct {
+		format    Format
+		threshold int
+		value     []byte
+	}
+	tests := [...]struct {
+		name       string
+		args       args
+		wantFormat Format
+	}{
+		{"none", args{FormatNone, 0, value}, FormatNone},
+		{"zstd", args{FormatZstd, 0, value}, FormatZstd},
+		{"gzip", args{FormatGzip, 0, value}, FormatGzip},
+		{"s2", args{FormatS2, 0, value}, FormatS2},
+		{"below threshold", args{FormatZstd, 1024, []byte("small")}, FormatNone},
+		{"empty", args{FormatZstd, 0, []byte{}}, FormatZstd},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			backend := NewRedis(newTestRedis(t))
+			cache, err := NewCodec(backend, tt.args.format, tt.args.threshold)
+			require.NoError(t, err)
+
+			require.NoError(t, cache.Set(ctx, "key", tt.args.value))
+
+			stored, err := backend.Get(ctx, "key")
+			require.NoError(t, err)
+			require.Equal(t, []byte{codecMagic, byte(tt.wantFormat)}, stored[:2])
+			if tt.wantFormat != FormatNone && len(tt.args.value) > 0 {
+				require.Less(t, len(stored), len(tt.args.value)+2, "should be compressed")
+			}
+
+			got, err := cache.Get(ctx, "key")
+			require.NoError(t, err)
+			require.Equal(t, tt.args.value, got)
+
+			values, err := cache.MGet(ctx, "key", "not-exists")
+			require.NoError(t, err)
+			require.Equal(t, [][]byte{tt.args.value, nil}, values)
+		})
+	}
+}
+
+func TestCodecLegacy(t *testing.T) {
+	value := []byte(`{"item_id":"1234"}`)
+	compressed, err := zstdCompress(value)
+	require.NoError(t, err)
+
+	type args struct {
+		stored []byte
+	}
+	tests := [...]struct {
+		name string
+		args args
+	}{
+		{"raw", args{value}},
+		{"zstd", args{compressed}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			backend := NewRedis(newTestRedis(t))
+			require.NoError(t, backend.Set(ctx, "key", tt.args.stored))
+
+			cache, err := NewCodec(backend, FormatS2, 0)
+			require.NoError(t, err)
+
+			got, err := cache.Get(ctx, "key")
+			require.NoError(t, err)
+			require.Equal(t, value, got)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, format := range []Format{FormatNone, FormatZstd, FormatGzip, FormatS2} {
+		got, err := ParseFormat(format.String())
+		require.NoError(t, err)
+		require.Equal(t, format, got)
+	}
+
+	_, err := ParseFormat("lz4")
+	require.Error(t, err)
+}
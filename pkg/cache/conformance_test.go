@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// conformance tests run against every cache implementation
+func TestConformance(t *testing.T) {
+	type args struct {
+		newCache func(t *testing.T) Interface
+	}
+	tests := [...]struct {
+		name string
+		args args
+	}{
+		{"bigcache", args{func(t *testing.T) Interface { return NewBigCache(context.Background()) }}},
+		{"redis", args{func(t *testing.T) Interface { return NewRedis(newTestRedis(t)) }}},
+		{"disk", args{func(t *testing.T) Interface { return newTestDisk(t, 0) }}},
+		{"redis cluster", args{func(t *testing.T) Interface { return NewRedis(newTestRedisCluster(t)) }}},
+		{"tiered", args{func(t *testing.T) Interface { return newTestTiered(t, newTestRedis(t)) }}},
+		{"tiered cluster", args{func(t *testing.T) Interface { return newTestTiered(t, newTestRedisCluster(t)) }}},
+		{"codec", args{func(t *testing.T) Interface {
+			cache, err := NewCodec(NewRedis(newTestRedis(t)), FormatZstd, 0)
+			require.NoError(t, err)
+			return cache
+		}}},
+		{"encrypted", args{func(t *testing.T) Interface {
+			return newTestEncrypted(t, NewRedis(newTestRedis(t)), map[byte]string{1: "secret1"}, 1)
+		}}},
+		{"memcache", args{func(t *testing.T) Interface {
+			client, _ := newTestMemcache(t)
+			return NewMemcache(client)
+		}}},
+		{"instrumented", args{func(t *testing.T) Interface { return NewInstrumented(newTestDisk(t, 0)) }}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, fn := range conformanceTests {
+				t.Run(name, func(t *testing.T) { fn(t, tt.args.newCache(t)) })
+			}
+		})
+	}
+}
+
+var conformanceTests = map[string]func(t *testing.T, cache Interface){
+	"set get":   testSetGet,
+	"delete":    testDelete,
+	"ttl":       testTTL,
+	"keys":      testKeys,
+	"mget mset": testMGetMSet,
+}
+
+func testSetGet(t *testing.T, cache Interface) {
+	ctx := context.Background()
+
+	_, err := cache.Get(ctx, "key")
+	require.ErrorIs(t, err, ErrNotExists)
+	require.False(t, cache.Has(ctx, "key"))
+
+	require.NoError(t, cache.Set(ctx, "key", []byte("value")))
+	got, err := cache.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), got)
+	require.True(t, cache.Has(ctx, "key"))
+
+	require.NoError(t, cache.Set(ctx, "key", []byte("updated")))
+	got, err = cache.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, []byte("updated"), got)
+}
+
+func testDelete(t *testing.T, cache Interface) {
+	ctx := context.Background()
+
+	require.NoError(t, cache.Set(ctx, "key1", []byte("value")))
+	require.NoError(t, cache.Set(ctx, "key2", []byte("value")))
+	require.NoError(t, cache.Delete(ctx, "key1", "key2", "not-exists"))
+
+	require.False(t, cache.Has(ctx, "key1"))
+	_, err := cache.Get(ctx, "key2")
+	require.ErrorIs(t, err, ErrNotExists)
+
+	require.NoError(t, cache.Delete(ctx))
+}
+
+func testTTL(t *testing.T, cache Interface) {
+	ctx := context.Background()
+
+	_, err := cache.TTL(ctx, "key")
+	require.ErrorIs(t, err, ErrNotExists)
+
+	require.NoError(t, cache.Set(ctx, "key", []byte("value")))
+	ttl, err := cache.TTL(ctx, "key")
+	require.NoError(t, err)
+	require.Zero(t, ttl, "no expiration")
+
+	require.NoError(t, cache.Set(ctx, "key", []byte("value"), WithExpire(time.Hour)))
+	ttl, err = cache.TTL(ctx, "key")
+	require.NoError(t, err)
+	require.InDelta(t, time.Hour, ttl, float64(time.Second*2))
+}
+
+func testKeys(t *testing.T, cache Interface) {
+	ctx := context.Background()
+
+	if _, err := cache.Keys(ctx, ""); err == ErrNotSupported {
+		t.Skip("keys not supported")
+	}
+
+	for _, key := range []string{"user/1", "user/2", "users", "other/1", "user*/3"} {
+		require.NoError(t, cache.Set(ctx, key, []byte("value")))
+	}
+
+	keys, err := cache.Keys(ctx, "user/")
+	require.NoError(t, err)
+	sort.Strings(keys)
+	require.Equal(t, []string{"user/1", "user/2"}, keys)
+
+	keys, err = cache.Keys(ctx, "user*")
+	require.NoError(t, err)
+	require.Equal(t, []string{"user*/3"}, keys, "prefix is not a pattern")
+
+	keys, err = cache.Keys(ctx, "not-exists")
+	require.NoError(t, err)
+	require.Empty(t, keys)
+}
+
+func testMGetMSet(t *testing.T, cache Interface) {
+	ctx := context.Background()
+
+	require.NoError(t, cache.MSet(ctx, map[string][]byte{
+		"key1": []byte("value1"),
+		"key2": []byte("value2"),
+	}, WithExpire(time.Hour)))
+
+	values, err := cache.MGet(ctx, "key1", "not-exists", "key2")
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("value1"), nil, []byte("value2")}, values)
+
+	ttl, err := cache.TTL(ctx, "key2")
+	require.NoError(t, err)
+	require.NotZero(t, ttl, "options should be applied to all values")
+
+	got, err := cache.Get(ctx, "key1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("value1"), got)
+}
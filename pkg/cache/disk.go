@@ -0,0 +1,371 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	diskHeaderSize      = 16 // expire at, written at in unix nano
+	diskCompactInterval = time.Minute * 10
+	diskCompactTxSize   = 1 << 20
+	diskEvictLowWater   = 90 // percent of maxSize to evict down to, so eviction does not scan the bucket on every set
+)
+
+var diskBucket = []byte("cache")
+
+// NewDisk return cache stored in bolt database file, entries are kept across restarts
+// expired entries are removed periodically and oldest entries are evicted down to 90% of maxSize if total size exceeds it; 0 for unlimited
+func NewDisk(ctx context.Context, path string, maxSize int64) (Interface, error) {
+	if err := compactDisk(path); err != nil {
+		return nil, errors.Wrapf(err, "compact failed: %s", path)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "open failed: %s", path)
+	}
+
+	d := &diskCacheImpl{
+		db:      db,
+		maxSize: maxSize,
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(diskBucket)
+		if err != nil {
+			return err
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			d.size += int64(len(k) + len(v))
+			return nil
+		})
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	go func() {
+		ticker := time.NewTicker(diskCompactInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				db.Close()
+				return
+			case <-ticker.C:
+				d.removeExpired()
+			}
+		}
+	}()
+
+	return d, nil
+}
+
+// compactDisk compact database file to reclaim space of deleted entries
+func compactDisk(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	src, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".compact"
+	dst, err := bolt.Open(tmp, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return err
+	}
+
+	if err := bolt.Compact(dst, src, diskCompactTxSize); err != nil {
+		dst.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+type diskCacheImpl struct {
+	db      *bolt.DB
+	maxSize int64
+	loads   loadGroup
+
+	mu        sync.Mutex // guard size and evictions
+	size      int64      // total bytes of keys and values
+	evictions int64      // entries removed to fit in maxSize
+}
+
+var _ Interface = (*diskCacheImpl)(nil)
+
+func (d *diskCacheImpl) backendStats() map[string]int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return map[string]int64{
+		"size":      d.size,
+		"max_size":  d.maxSize,
+		"evictions": d.evictions,
+	}
+}
+
+func (d *diskCacheImpl) Set(ctx context.Context, key string, value []byte, opts ...setOption) error {
+	return d.MSet(ctx, map[string][]byte{key: value}, opts...)
+}
+
+func (d *diskCacheImpl) MSet(ctx context.Context, values map[string][]byte, opts ...setOption) error {
+	option := applySetOptions(opts)
+
+	now := time.Now()
+	var expireAt int64
+	if option.expire != 0 {
+		expireAt = now.Add(option.expire).UnixNano()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// size and evictions are applied after commit, so failed transaction does not skew them
+	var delta, evictions int64
+	if err := d.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(diskBucket)
+		delta, evictions = 0, 0
+
+		for key, value := range values {
+			data := make([]byte, diskHeaderSize+len(value))
+			binary.BigEndian.PutUint64(data, uint64(expireAt))
+			binary.BigEndian.PutUint64(data[8:], uint64(now.UnixNano()))
+			copy(data[diskHeaderSize:], value)
+
+			if old := b.Get([]byte(key)); old != nil {
+				delta -= int64(len(key) + len(old))
+			}
+
+			if err := b.Put([]byte(key), data); err != nil {
+				return err
+			}
+			delta += int64(len(key) + len(data))
+		}
+
+		if size := d.size + delta; d.maxSize > 0 && size > d.maxSize {
+			freed, n, err := evictDisk(b, now, size-d.maxSize*diskEvictLowWater/100)
+			if err != nil {
+				return err
+			}
+			delta -= freed
+			evictions = n
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	d.size += delta
+	d.evictions += evictions
+	return nil
+}
+
+// evictDisk remove expired entries and then oldest entries until at least need bytes are freed
+// return freed bytes and number of removed entries
+func evictDisk(b *bolt.Bucket, now time.Time, need int64) (freed int64, evictions int64, err error) {
+	type entry struct {
+		key       []byte
+		size      int64
+		writtenAt int64
+	}
+
+	var entries []entry
+	if err := b.ForEach(func(k, v []byte) error {
+		entries = append(entries, entry{
+			key:       append([]byte(nil), k...),
+			size:      int64(len(k) + len(v)),
+			writtenAt: int64(binary.BigEndian.Uint64(v[8:])),
+		})
+		if expired(v, now) {
+			entries[len(entries)-1].writtenAt = 0 // evict first
+		}
+		return nil
+	}); err != nil {
+		return 0, 0, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].writtenAt < entries[j].writtenAt })
+
+	for _, e := range entries {
+		if freed >= need {
+			break
+		}
+
+		if err := b.Delete(e.key); err != nil {
+			return 0, 0, err
+		}
+		freed += e.size
+		evictions++
+	}
+
+	return freed, evictions, nil
+}
+
+// removeExpired remove expired entries
+func (d *diskCacheImpl) removeExpired() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	var freed int64
+	if err := d.db.Update(func(tx *bolt.Tx) error {
+		freed = 0
+		c := tx.Bucket(diskBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if !expired(v, now) {
+				continue
+			}
+
+			size := int64(len(k) + len(v))
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			freed += size
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	d.size -= freed
+	return nil
+}
+
+// expired return true if the entry is expired
+func expired(v []byte, now time.Time) bool {
+	expireAt := int64(binary.BigEndian.Uint64(v))
+	return expireAt != 0 && expireAt < now.UnixNano()
+}
+
+func (d *diskCacheImpl) Get(ctx context.Context, key string) ([]byte, error) {
+	values, err := d.MGet(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if values[0] == nil {
+		return nil, ErrNotExists
+	}
+	return values[0], nil
+}
+
+func (d *diskCacheImpl) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	now := time.Now()
+
+	if err := d.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(diskBucket)
+		for i, key := range keys {
+			v := b.Get([]byte(key))
+			if v == nil || expired(v, now) {
+				continue
+			}
+
+			values[i] = append([]byte{}, v[diskHeaderSize:]...)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func (d *diskCacheImpl) Has(ctx context.Context, key string) bool {
+	_, err := d.Get(ctx, key)
+	return err == nil
+}
+
+func (d *diskCacheImpl) Delete(ctx context.Context, keys ...string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var freed int64
+	if err := d.db.Update(func(tx *bolt.Tx) error {
+		freed = 0
+		b := tx.Bucket(diskBucket)
+		for _, key := range keys {
+			v := b.Get([]byte(key))
+			if v == nil {
+				continue
+			}
+
+			size := int64(len(key) + len(v))
+			if err := b.Delete([]byte(key)); err != nil {
+				return err
+			}
+			freed += size
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	d.size -= freed
+	return nil
+}
+
+func (d *diskCacheImpl) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl time.Duration
+	if err := d.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(diskBucket).Get([]byte(key))
+		now := time.Now()
+		if v == nil || expired(v, now) {
+			return ErrNotExists
+		}
+
+		if expireAt := int64(binary.BigEndian.Uint64(v)); expireAt != 0 {
+			ttl = time.Unix(0, expireAt).Sub(now)
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return ttl, nil
+}
+
+func (d *diskCacheImpl) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	now := time.Now()
+
+	if err := d.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(diskBucket).Cursor()
+		for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+			if !expired(v, now) {
+				keys = append(keys, string(k))
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (d *diskCacheImpl) GetOrLoad(ctx context.Context, key string, loader LoaderFunc, opts ...setOption) ([]byte, error) {
+	return d.loads.getOrLoad(ctx, d, key, loader, opts)
+}
@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDisk(t *testing.T, maxSize int64) Interface {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cache, err := NewDisk(ctx, filepath.Join(t.TempDir(), "cache.db"), maxSize)
+	require.NoError(t, err)
+	return cache
+}
+
+func TestDiskPersistent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cache, err := NewDisk(ctx, path, 0)
+	require.NoError(t, err)
+	require.NoError(t, cache.Set(ctx, "hello", []byte("world")))
+	require.NoError(t, cache.Set(ctx, "expired", []byte("world"), WithExpire(time.Millisecond)))
+	cancel()
+
+	// reopen; waits until previous database closed
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	cache, err = NewDisk(ctx, path, 0)
+	require.NoError(t, err)
+
+	got, err := cache.Get(ctx, "hello")
+	require.NoError(t, err)
+	require.Equal(t, []byte("world"), got)
+	require.False(t, cache.Has(ctx, "expired"), "expired entry should not be served")
+}
+
+func TestDiskExpire(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestDisk(t, 0)
+
+	require.NoError(t, cache.Set(ctx, "hello", []byte("world"), WithExpire(100*time.Millisecond)))
+	require.True(t, cache.Has(ctx, "hello"))
+
+	time.Sleep(150 * time.Millisecond)
+	_, err := cache.Get(ctx, "hello")
+	require.ErrorIs(t, err, ErrNotExists)
+
+	require.NoError(t, cache.(*diskCacheImpl).removeExpired())
+	require.Zero(t, cache.(*diskCacheImpl).size, "expired entry should be removed")
+}
+
+func TestDiskMaxSize(t *testing.T) {
+	ctx := context.Background()
+	value := make([]byte, 1000)
+	entrySize := int64(diskHeaderSize + len(value) + len("key-00"))
+	cache := newTestDisk(t, 10*entrySize)
+
+	for i := 0; i < 11; i++ {
+		require.NoError(t, cache.Set(ctx, fmt.Sprintf("key-%02d", i), value))
+	}
+
+	d := cache.(*diskCacheImpl)
+	require.Equal(t, int64(2), d.evictions, "should evict down to low water mark")
+	require.Equal(t, 9*entrySize, d.size)
+
+	require.NoError(t, cache.Set(ctx, "key-11", value))
+	require.Equal(t, int64(2), d.evictions, "should not evict until exceeds max size again")
+
+	for i := 0; i < 2; i++ {
+		require.False(t, cache.Has(ctx, fmt.Sprintf("key-%02d", i)), "oldest entries should be evicted")
+	}
+	for i := 2; i < 12; i++ {
+		require.True(t, cache.Has(ctx, fmt.Sprintf("key-%02d", i)))
+	}
+}
+
+func TestDiskSizeFailedSet(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestDisk(t, 0)
+
+	require.NoError(t, cache.Set(ctx, "key", []byte("value")))
+	size := cache.(*diskCacheImpl).size
+
+	require.Error(t, cache.MSet(ctx, map[string][]byte{"key": []byte("updated value"), "": []byte("value")}))
+	require.Equal(t, size, cache.(*diskCacheImpl).size, "size should not change if transaction failed")
+}
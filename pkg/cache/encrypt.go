@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/hkdf"
+)
+
+// encrypted value: version, key id, nonce, sealed value
+const encryptVersion = 1
+
+// aeadCacheSize ciphers of recently used scopes kept to skip key derivation
+const aeadCacheSize = 1024
+
+// NewEncrypted return cache which encrypts values with AES-GCM before store them to c
+// encryption key is derived from the secret of key id and scope of the cache key, e.g. user of the key by KeyBuilder.Scope
+// new values are encrypted with secret of current key id, values encrypted with other key ids in secrets are still readable
+// values which are not encrypted or can not be decrypted are treated as not exists
+// stack compression over this, e.g. NewCodec(NewEncrypted(c, ...)), encrypted values are not compressible
+func NewEncrypted(c Interface, secrets map[byte]string, current byte, scope func(key string) string) (Interface, error) {
+	if _, ok := secrets[current]; !ok {
+		return nil, errors.Errorf("secret of key id %d required", current)
+	}
+
+	return &encryptedCacheImpl{
+		cache:   c,
+		secrets: secrets,
+		current: current,
+		scope:   scope,
+		aeads:   NewLRU[string, cipher.AEAD](aeadCacheSize, 0),
+	}, nil
+}
+
+type encryptedCacheImpl struct {
+	cache   Interface
+	secrets map[byte]string
+	current byte
+	scope   func(key string) string
+	aeads   *LRU[string, cipher.AEAD] // key id and scope -> cipher
+	loads   loadGroup
+}
+
+var _ Interface = (*encryptedCacheImpl)(nil)
+
+// aead return cipher with key derived from secret of key id and scope of the key
+func (e *encryptedCacheImpl) aead(keyID byte, key string) (cipher.AEAD, error) {
+	secret, ok := e.secrets[keyID]
+	if !ok {
+		return nil, errors.Errorf("unknown key id: %d", keyID)
+	}
+
+	scope := e.scope(key)
+	cacheKey := fmt.Sprintf("%d/%s", keyID, scope)
+	if aead, ok := e.aeads.Get(cacheKey); ok {
+		return aead, nil
+	}
+
+	derived := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), nil, []byte(scope)), derived); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	e.aeads.Add(cacheKey, aead)
+	return aead, nil
+}
+
+// encrypt return sealed value; cache key is authenticated, so value can not be moved to other key
+func (e *encryptedCacheImpl) encrypt(key string, value []byte) ([]byte, error) {
+	aead, err := e.aead(e.current, key)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 2+aead.NonceSize(), 2+aead.NonceSize()+len(value)+aead.Overhead())
+	data[0] = encryptVersion
+	data[1] = e.current
+	if _, err := rand.Read(data[2:]); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(data, data[2:], value, []byte(key)), nil
+}
+
+// decrypt return ErrNotExists if the value can not be decrypted
+func (e *encryptedCacheImpl) decrypt(key string, data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != encryptVersion {
+		return nil, ErrNotExists
+	}
+
+	aead, err := e.aead(data[1], key)
+	if err != nil {
+		return nil, ErrNotExists
+	}
+
+	nonce := data[2:]
+	if len(nonce) < aead.NonceSize() {
+		return nil, ErrNotExists
+	}
+	nonce, sealed := nonce[:aead.NonceSize()], nonce[aead.NonceSize():]
+
+	value, err := aead.Open(nil, nonce, sealed, []byte(key))
+	if err != nil {
+		return nil, ErrNotExists
+	}
+
+	// nil is not exists for MGet
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+func (e *encryptedCacheImpl) Set(ctx context.Context, key string, value []byte, opts ...setOption) error {
+	data, err := e.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+
+	return e.cache.Set(ctx, key, data, opts...)
+}
+
+func (e *encryptedCacheImpl) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := e.cache.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.decrypt(key, data)
+}
+
+func (e *encryptedCacheImpl) Has(ctx context.Context, key string) bool {
+	_, err := e.Get(ctx, key)
+	return err == nil
+}
+
+func (e *encryptedCacheImpl) Delete(ctx context.Context, keys ...string) error {
+	return e.cache.Delete(ctx, keys...)
+}
+
+func (e *encryptedCacheImpl) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return e.cache.TTL(ctx, key)
+}
+
+func (e *encryptedCacheImpl) Keys(ctx context.Context, prefix string) ([]string, error) {
+	return e.cache.Keys(ctx, prefix)
+}
+
+func (e *encryptedCacheImpl) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	values, err := e.cache.MGet(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, data := range values {
+		if data == nil {
+			continue
+		}
+
+		values[i], _ = e.decrypt(keys[i], data)
+	}
+	return values, nil
+}
+
+func (e *encryptedCacheImpl) MSet(ctx context.Context, values map[string][]byte, opts ...setOption) error {
+	encrypted := make(map[string][]byte, len(values))
+	for key, value := range values {
+		data, err := e.encrypt(key, value)
+		if err != nil {
+			return err
+		}
+		encrypted[key] = data
+	}
+
+	return e.cache.MSet(ctx, encrypted, opts...)
+}
+
+func (e *encryptedCacheImpl) GetOrLoad(ctx context.Context, key string, loader LoaderFunc, opts ...setOption) ([]byte, error) {
+	return e.loads.getOrLoad(ctx, e, key, loader, opts)
+}
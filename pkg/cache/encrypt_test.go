@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEncrypted(t *testing.T, c Interface, secrets map[byte]string, current byte) Interface {
+	keys := NewKeyBuilder("app", 1, "secret")
+	encrypted, err := NewEncrypted(c, secrets, current, keys.Scope)
+	require.NoError(t, err)
+	return encrypted
+}
+
+func TestEncrypted(t *testing.T) {
+	ctx := context.Background()
+	keys := NewKeyBuilder("app", 1, "secret")
+	key := keys.Key(ctx, "user1", "favorites")
+	value := []byte(`{"item_id":"1234"}`)
+
+	backend := NewRedis(newTestRedis(t))
+	c := newTestEncrypted(t, backend, map[byte]string{1: "secret1"}, 1)
+
+	require.NoError(t, c.Set(ctx, key, value))
+	stored, err := backend.Get(ctx, key)
+	require.NoError(t, err)
+	require.NotContains(t, string(stored), "item_id", "value should be encrypted")
+
+	got, err := c.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, value, got)
+
+	// value can not be moved to other user
+	other := keys.Key(ctx, "user2", "favorites")
+	require.NoError(t, backend.Set(ctx, other, stored))
+	_, err = c.Get(ctx, other)
+	require.ErrorIs(t, err, ErrNotExists)
+	require.False(t, c.Has(ctx, other))
+
+	// plain value is not exists
+	require.NoError(t, backend.Set(ctx, key, value))
+	_, err = c.Get(ctx, key)
+	require.ErrorIs(t, err, ErrNotExists)
+}
+
+func TestEncryptedRotation(t *testing.T) {
+	ctx := context.Background()
+	key := NewKeyBuilder("app", 1, "secret").Key(ctx, "user1", "favorites")
+	backend := NewRedis(newTestRedis(t))
+
+	old := newTestEncrypted(t, backend, map[byte]string{1: "secret1"}, 1)
+	require.NoError(t, old.Set(ctx, key, []byte("value")))
+
+	rotated := newTestEncrypted(t, backend, map[byte]string{1: "secret1", 2: "secret2"}, 2)
+	got, err := rotated.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), got, "value of previous key id should be readable")
+
+	require.NoError(t, rotated.Set(ctx, key, []byte("value")))
+	stored, err := backend.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, byte(2), stored[1], "should be encrypted with current key id")
+
+	_, err = old.Get(ctx, key)
+	require.ErrorIs(t, err, ErrNotExists, "unknown key id")
+
+	_, err = NewEncrypted(backend, map[byte]string{1: "secret1"}, 2, nil)
+	require.Error(t, err)
+}
+
+func TestEncryptedWithCodec(t *testing.T) {
+	ctx := context.Background()
+	value := sampleArticles(100)
+
+	backend := NewRedis(newTestRedis(t))
+	c, err := NewCodec(newTestEncrypted(t, backend, map[byte]string{1: "secret1"}, 1), FormatZstd, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set(ctx, "key", value))
+	stored, err := backend.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Less(t, len(stored), len(value)/2, "should be compressed before encryption")
+
+	got, err := c.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, value, got)
+}
+
+func TestEncryptedManyScopes(t *testing.T) {
+	ctx := context.Background()
+	keys := NewKeyBuilder("app", 1, "secret")
+	c := newTestEncrypted(t, NewBigCache(ctx), map[byte]string{1: "secret"}, 1)
+
+	for i := 0; i < aeadCacheSize*2; i++ {
+		require.NoError(t, c.Set(ctx, keys.Key(ctx, fmt.Sprintf("user%d", i), "favorites"), []byte("value")))
+	}
+	require.Equal(t, aeadCacheSize, c.(*encryptedCacheImpl).aeads.Len(), "ciphers should be bounded")
+
+	// cipher of evicted scope is derived again
+	got, err := c.Get(ctx, keys.Key(ctx, "user0", "favorites"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), got)
+}
@@ -0,0 +1,244 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+)
+
+// operations measured by Instrumented
+var instrumentedOps = []string{"set", "get", "has", "delete", "ttl", "keys", "mget", "mset", "load"}
+
+// Stats counters of instrumented cache
+type Stats struct {
+	Hits         int64                   `json:"hits"`
+	Misses       int64                   `json:"misses"`
+	Errors       int64                   `json:"errors"`
+	BytesRead    int64                   `json:"bytes_read"`
+	BytesWritten int64                   `json:"bytes_written"`
+	Latency      map[string]LatencyStats `json:"latency"`           // by operation, load is latency of loaders of GetOrLoad
+	Backend      map[string]int64        `json:"backend,omitempty"` // counters of backend, e.g. evictions
+}
+
+// HitRatio return ratio of hits in lookups, 0 if no lookups
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// LatencyStats latency of cache operation
+type LatencyStats struct {
+	Calls int64         `json:"calls"`
+	Total time.Duration `json:"total_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+// statsReporter backend which reports its own counters
+type statsReporter interface {
+	backendStats() map[string]int64
+}
+
+// Instrumented cache which counts hits, misses, bytes and latency of operations on c
+// wrap backend with it to measure what is actually stored, e.g. NewCodec(NewInstrumented(backend), ...)
+type Instrumented struct {
+	cache Interface
+	loads loadGroup
+
+	hits         atomic.Int64
+	misses       atomic.Int64
+	errors       atomic.Int64
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+	latency      map[string]*latency
+}
+
+var _ Interface = (*Instrumented)(nil)
+
+type latency struct {
+	calls atomic.Int64
+	total atomic.Int64
+	max   atomic.Int64
+}
+
+func (l *latency) observe(d time.Duration) {
+	l.calls.Add(1)
+	l.total.Add(int64(d))
+	for {
+		cur := l.max.Load()
+		if int64(d) <= cur || l.max.CompareAndSwap(cur, int64(d)) {
+			return
+		}
+	}
+}
+
+// NewInstrumented return cache which measures operations on c
+func NewInstrumented(c Interface) *Instrumented {
+	i := &Instrumented{
+		cache:   c,
+		latency: make(map[string]*latency, len(instrumentedOps)),
+	}
+	for _, op := range instrumentedOps {
+		i.latency[op] = &latency{}
+	}
+
+	return i
+}
+
+// Stats return current counters
+func (i *Instrumented) Stats() Stats {
+	stats := Stats{
+		Hits:         i.hits.Load(),
+		Misses:       i.misses.Load(),
+		Errors:       i.errors.Load(),
+		BytesRead:    i.bytesRead.Load(),
+		BytesWritten: i.bytesWritten.Load(),
+		Latency:      make(map[string]LatencyStats, len(i.latency)),
+	}
+
+	for op, l := range i.latency {
+		stats.Latency[op] = LatencyStats{
+			Calls: l.calls.Load(),
+			Total: time.Duration(l.total.Load()),
+			Max:   time.Duration(l.max.Load()),
+		}
+	}
+
+	if r, ok := i.cache.(statsReporter); ok {
+		stats.Backend = r.backendStats()
+	}
+
+	return stats
+}
+
+// String return stats as json, so it can be published by expvar
+func (i *Instrumented) String() string {
+	buf, _ := json.Marshal(i.Stats())
+	return string(buf)
+}
+
+// observe record latency of operation since start, and error other than ErrNotExists
+func (i *Instrumented) observe(op string, start time.Time, err error) {
+	i.latency[op].observe(time.Since(start))
+	if err != nil && err != ErrNotExists {
+		i.errors.Add(1)
+	}
+}
+
+func (i *Instrumented) Set(ctx context.Context, key string, value []byte, opts ...setOption) error {
+	start := time.Now()
+	err := i.cache.Set(ctx, key, value, opts...)
+	i.observe("set", start, err)
+	if err != nil {
+		return err
+	}
+
+	i.bytesWritten.Add(int64(len(value)))
+	return nil
+}
+
+func (i *Instrumented) Get(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	value, err := i.cache.Get(ctx, key)
+	i.observe("get", start, err)
+
+	switch err {
+	case nil:
+		i.hits.Add(1)
+		i.bytesRead.Add(int64(len(value)))
+	case ErrNotExists:
+		i.misses.Add(1)
+	}
+	return value, err
+}
+
+func (i *Instrumented) Has(ctx context.Context, key string) bool {
+	start := time.Now()
+	ok := i.cache.Has(ctx, key)
+	i.observe("has", start, nil)
+	return ok
+}
+
+func (i *Instrumented) Delete(ctx context.Context, keys ...string) error {
+	start := time.Now()
+	err := i.cache.Delete(ctx, keys...)
+	i.observe("delete", start, err)
+	return err
+}
+
+func (i *Instrumented) TTL(ctx context.Context, key string) (time.Duration, error) {
+	start := time.Now()
+	ttl, err := i.cache.TTL(ctx, key)
+	i.observe("ttl", start, err)
+	return ttl, err
+}
+
+func (i *Instrumented) Keys(ctx context.Context, prefix string) ([]string, error) {
+	start := time.Now()
+	keys, err := i.cache.Keys(ctx, prefix)
+	i.observe("keys", start, err)
+	return keys, err
+}
+
+func (i *Instrumented) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	start := time.Now()
+	values, err := i.cache.MGet(ctx, keys...)
+	i.observe("mget", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range values {
+		if value == nil {
+			i.misses.Add(1)
+			continue
+		}
+		i.hits.Add(1)
+		i.bytesRead.Add(int64(len(value)))
+	}
+	return values, nil
+}
+
+func (i *Instrumented) MSet(ctx context.Context, values map[string][]byte, opts ...setOption) error {
+	start := time.Now()
+	err := i.cache.MSet(ctx, values, opts...)
+	i.observe("mset", start, err)
+	if err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		i.bytesWritten.Add(int64(len(value)))
+	}
+	return nil
+}
+
+func (i *Instrumented) GetOrLoad(ctx context.Context, key string, loader LoaderFunc, opts ...setOption) ([]byte, error) {
+	return i.loads.getOrLoad(ctx, i, key, i.observeLoader(loader), opts)
+}
+
+// ObserveLoads return c whose loaders of GetOrLoad are measured as load of i
+// layers over i, e.g. codec, load values by themselves, so wrap the outermost layer with it
+func (i *Instrumented) ObserveLoads(c Interface) Interface {
+	return &loadObserver{Interface: c, stats: i}
+}
+
+type loadObserver struct {
+	Interface
+	stats *Instrumented
+}
+
+func (o *loadObserver) GetOrLoad(ctx context.Context, key string, loader LoaderFunc, opts ...setOption) ([]byte, error) {
+	return o.Interface.GetOrLoad(ctx, key, o.stats.observeLoader(loader), opts...)
+}
+
+func (i *Instrumented) observeLoader(loader LoaderFunc) LoaderFunc {
+	return func(ctx context.Context) ([]byte, error) {
+		start := time.Now()
+		value, err := loader(ctx)
+		i.latency["load"].observe(time.Since(start))
+		return value, err
+	}
+}
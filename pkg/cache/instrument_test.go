@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumented(t *testing.T) {
+	type args struct {
+		backend func(t *testing.T) Interface
+		stats   []string // expected backend stats
+	}
+	tests := [...]struct {
+		name string
+		args args
+	}{
+		{"bigcache", args{func(t *testing.T) Interface { return NewBigCache(context.Background()) }, []string{"entries", "hits", "evictions"}}},
+		{"disk", args{func(t *testing.T) Interface { return newTestDisk(t, 0) }, []string{"size", "evictions"}}},
+		{"tiered", args{func(t *testing.T) Interface { return newTestTiered(t, newTestRedis(t)) }, []string{"l1_entries", "l1_evictions"}}},
+		{"redis", args{func(t *testing.T) Interface { return NewRedis(newTestRedis(t)) }, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cache := NewInstrumented(tt.args.backend(t))
+
+			require.NoError(t, cache.Set(ctx, "key", []byte("value")))
+			_, err := cache.Get(ctx, "key")
+			require.NoError(t, err)
+			_, err = cache.Get(ctx, "missing")
+			require.ErrorIs(t, err, ErrNotExists)
+			_, err = cache.MGet(ctx, "key", "missing")
+			require.NoError(t, err)
+			_, err = cache.GetOrLoad(ctx, "loaded", func(ctx context.Context) ([]byte, error) { return []byte("loaded"), nil })
+			require.NoError(t, err)
+
+			stats := cache.Stats()
+			require.Equal(t, int64(2), stats.Hits)
+			require.Equal(t, int64(3), stats.Misses)
+			require.Equal(t, int64(0), stats.Errors)
+			require.Equal(t, int64(len("value")*2), stats.BytesRead)
+			require.Equal(t, int64(len("value")+len("loaded")), stats.BytesWritten)
+			require.Equal(t, int64(3), stats.Latency["get"].Calls)
+			require.Equal(t, int64(1), stats.Latency["load"].Calls)
+			require.InDelta(t, 0.4, stats.HitRatio(), 0.001)
+			for _, name := range tt.args.stats {
+				require.Contains(t, stats.Backend, name)
+			}
+
+			var got Stats
+			require.NoError(t, json.Unmarshal([]byte(cache.String()), &got), "should be published as json")
+			require.Equal(t, stats.Hits, got.Hits)
+		})
+	}
+}
+
+func TestBigCacheEvictions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cache, err := NewBigCacheWithConfig(ctx, 1, 1)
+	require.NoError(t, err)
+
+	value := make([]byte, 64<<10)
+	for i := 0; i < 64; i++ {
+		require.NoError(t, cache.Set(ctx, string(rune('a'+i)), value))
+	}
+
+	require.NotZero(t, NewInstrumented(cache).Stats().Backend["evictions"], "entries should be evicted for space")
+}
+
+func TestInstrumentedObserveLoads(t *testing.T) {
+	ctx := context.Background()
+	stats := NewInstrumented(NewBigCache(ctx))
+	codec, err := NewCodec(stats, FormatZstd, 0)
+	require.NoError(t, err)
+	cache := stats.ObserveLoads(codec)
+
+	_, err = cache.GetOrLoad(ctx, "loaded", func(ctx context.Context) ([]byte, error) {
+		time.Sleep(10 * time.Millisecond)
+		return []byte("loaded"), nil
+	})
+	require.NoError(t, err)
+
+	load := stats.Stats().Latency["load"]
+	require.Equal(t, int64(1), load.Calls, "loader of outer layer should be measured")
+	require.GreaterOrEqual(t, load.Total, 10*time.Millisecond)
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// migratedKeysSize keys whose migration is remembered, evicted keys are checked again when they are built
+const migratedKeysSize = 10000
+
+// KeyBuilder build cache keys namespaced by application and schema version
+// user identity such as access token is hashed with secret, so keys do not reveal it
+type KeyBuilder struct {
+	app    string
+	prefix string
+	secret []byte
+
+	previous        string // prefix of previous version, empty if not migrated
+	previousVersion int
+
+	cache    Interface
+	legacy   []func(identity string, name string) string
+	migrated *LRU[string, struct{}] // keys whose legacy keys are migrated
+}
+
+// NewKeyBuilder return key builder; keys look like app:v1:{hash-of-identity}:name
+// hash of identity is hash tag, so keys of an identity are in the same slot of redis cluster
+func NewKeyBuilder(app string, version int, secret string) *KeyBuilder {
+	return &KeyBuilder{
+		app:    app,
+		prefix: fmt.Sprintf("%s:v%d", app, version),
+		secret: []byte(secret),
+	}
+}
+
+// WithLegacy move value of legacy key to new key in c, when the key is built first time
+// legacy keys added first are moved first, value of the new key is not overwritten by later ones
+// legacy values which can not be read through c, e.g. plain text values under encryption, are removed
+func (b *KeyBuilder) WithLegacy(c Interface, legacy func(identity string, name string) string) *KeyBuilder {
+	b.cache = c
+	b.legacy = append(b.legacy, legacy)
+	if b.migrated == nil {
+		b.migrated = NewLRU[string, struct{}](migratedKeysSize, 0)
+	}
+	return b
+}
+
+// WithPrevious move value of key built by previous version of builder to new key in c, when the key is built first time
+// Scope recognize keys of previous version, so encrypted values of them are moved
+func (b *KeyBuilder) WithPrevious(c Interface, version int) *KeyBuilder {
+	b.previous = fmt.Sprintf("%s:v%d", b.app, version)
+	b.previousVersion = version
+	return b.WithLegacy(c, func(identity string, name string) string { return b.previousPrefix(identity) + name })
+}
+
+// Root return prefix of all keys built by this builder
+func (b *KeyBuilder) Root() string { return b.prefix + ":" }
+
+// PreviousRoot return prefix of all keys of previous version, empty if not set by WithPrevious
+func (b *KeyBuilder) PreviousRoot() string {
+	if b.previous == "" {
+		return ""
+	}
+	return b.previous + ":"
+}
+
+// Prefix return prefix of all keys of identity
+func (b *KeyBuilder) Prefix(identity string) string {
+	return fmt.Sprintf("%s:{%s}:", b.prefix, b.hash(identity))
+}
+
+// previousPrefix return prefix of keys of identity built by previous version; version 1 had no hash tag
+func (b *KeyBuilder) previousPrefix(identity string) string {
+	if b.previousVersion == 1 {
+		return fmt.Sprintf("%s:%s:", b.previous, b.hash(identity))
+	}
+	return fmt.Sprintf("%s:{%s}:", b.previous, b.hash(identity))
+}
+
+func (b *KeyBuilder) hash(identity string) string {
+	mac := hmac.New(sha256.New, b.secret)
+	mac.Write([]byte(identity))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Scope return prefix of identity which the key belongs to, empty if the key is not built by this builder
+// keys of previous version are in scope of the previous version, as they were
+func (b *KeyBuilder) Scope(key string) string {
+	for _, prefix := range []string{b.prefix, b.previous} {
+		if prefix == "" {
+			continue
+		}
+
+		rest, ok := strings.CutPrefix(key, prefix+":")
+		if !ok {
+			continue
+		}
+
+		hash, _, ok := strings.Cut(rest, ":")
+		if !ok {
+			return ""
+		}
+
+		return fmt.Sprintf("%s:%s:", prefix, hash)
+	}
+
+	return ""
+}
+
+// Key return key of name for identity
+// failed migration of legacy keys is tried again when the key is built next time
+func (b *KeyBuilder) Key(ctx context.Context, identity string, name string) string {
+	key := b.Prefix(identity) + name
+
+	if b.legacy != nil {
+		if _, done := b.migrated.Get(key); !done {
+			var err error
+			for _, legacy := range b.legacy {
+				if err = b.migrate(ctx, legacy(identity, name), key); err != nil {
+					break
+				}
+			}
+
+			if err == nil {
+				b.migrated.Add(key, struct{}{})
+			}
+		}
+	}
+
+	return key
+}
+
+// migrate move value of legacy key to key
+func (b *KeyBuilder) migrate(ctx context.Context, legacy string, key string) error {
+	if err := migrateKey(ctx, b.cache, legacy, key); err != nil {
+		return err
+	}
+
+	// refresh time was kept in sidecar key by previous versions, migrated value is refreshed on next load
+	return b.cache.Delete(ctx, legacy+"/refresh")
+}
+
+// migrateKey move value with its expiration
+func migrateKey(ctx context.Context, c Interface, from string, to string) error {
+	value, err := c.Get(ctx, from)
+	switch {
+	case err == ErrNotExists:
+		// value can not be read, e.g. plain text value of legacy key read by encrypted cache, is removed not to keep it in the backend
+		return c.Delete(ctx, from)
+	case err != nil:
+		return err
+	}
+
+	if !c.Has(ctx, to) {
+		ttl, err := c.TTL(ctx, from)
+		if err != nil {
+			return err
+		}
+
+		if err := c.Set(ctx, to, value, WithExpire(ttl)); err != nil {
+			return err
+		}
+	}
+
+	return c.Delete(ctx, from)
+}
@@ -0,0 +1,141 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyBuilder(t *testing.T) {
+	ctx := context.Background()
+	token := "1234-abcd-access-token"
+
+	keys := NewKeyBuilder("app", 1, "secret")
+	key := keys.Key(ctx, token, "favorites")
+	require.True(t, strings.HasPrefix(key, "app:v1:"), key)
+	require.True(t, strings.HasSuffix(key, ":favorites"), key)
+	require.NotContains(t, key, token, "identity should be hashed")
+	require.True(t, strings.HasPrefix(key, keys.Prefix(token)))
+	require.Equal(t, keys.Prefix(token), keys.Scope(key))
+	require.Equal(t, "", keys.Scope("other:v1:hash:favorites"))
+	require.Equal(t, redisSlot(keys.Prefix(token)), redisSlot(keys.Key(ctx, token, "trash")), "keys of identity should be in the same slot")
+
+	require.Equal(t, key, keys.Key(ctx, token, "favorites"))
+	require.NotEqual(t, key, keys.Key(ctx, "other-token", "favorites"))
+	require.NotEqual(t, key, NewKeyBuilder("app", 1, "other-secret").Key(ctx, token, "favorites"))
+	require.NotEqual(t, key, NewKeyBuilder("app", 2, "secret").Key(ctx, token, "favorites"))
+}
+
+func TestKeyBuilderLegacy(t *testing.T) {
+	ctx := context.Background()
+	token := "1234-abcd-access-token"
+	s := newTestRedis(t)
+	c := NewRedis(s)
+
+	legacy := func(identity string, name string) string { return fmt.Sprintf("%s/%s", identity, name) }
+	require.NoError(t, c.Set(ctx, "1234-abcd-access-token/favorites", []byte("articles"), WithExpire(time.Hour)))
+	require.NoError(t, c.Set(ctx, "1234-abcd-access-token/favorites/refresh", []byte("refresh")))
+
+	keys := NewKeyBuilder("app", 1, "secret").WithLegacy(c, legacy)
+	key := keys.Key(ctx, token, "favorites")
+
+	got, err := c.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("articles"), got)
+
+	ttl, err := c.TTL(ctx, key)
+	require.NoError(t, err)
+	require.InDelta(t, time.Hour, ttl, float64(time.Second), "expiration should be kept")
+
+	legacyKeys, err := c.Keys(ctx, token)
+	require.NoError(t, err)
+	require.Empty(t, legacyKeys, "legacy keys should be removed")
+
+	// not exists legacy key
+	key = keys.Key(ctx, token, "trash")
+	require.False(t, c.Has(ctx, key))
+}
+
+func TestKeyBuilderLegacyEncrypted(t *testing.T) {
+	ctx := context.Background()
+	token := "1234-abcd-access-token"
+	backend := NewRedis(newTestRedis(t))
+
+	keys := NewKeyBuilder("app", 2, "secret")
+	c, err := NewEncrypted(backend, map[byte]string{1: "secret1"}, 1, keys.Scope)
+	require.NoError(t, err)
+
+	// plain text value of legacy key can not be read by encrypted cache
+	legacy := func(identity string, name string) string { return fmt.Sprintf("%s/%s", identity, name) }
+	require.NoError(t, backend.Set(ctx, "1234-abcd-access-token/favorites", []byte("articles"), WithExpire(time.Hour)))
+
+	key := keys.WithLegacy(c, legacy).Key(ctx, token, "favorites")
+	require.False(t, c.Has(ctx, key))
+
+	legacyKeys, err := backend.Keys(ctx, token)
+	require.NoError(t, err)
+	require.Empty(t, legacyKeys, "legacy keys with access token should be removed")
+}
+
+func TestKeyBuilderPrevious(t *testing.T) {
+	ctx := context.Background()
+	token := "1234-abcd-access-token"
+	backend := NewRedis(newTestRedis(t))
+	secrets := map[byte]string{1: "secret1"}
+
+	keys := NewKeyBuilder("app", 2, "secret")
+	hash := strings.Trim(strings.TrimPrefix(keys.Prefix(token), "app:v2:"), "{}:")
+
+	// value stored by version 1, which had no hash tag and was encrypted in its scope
+	previous := "app:v1:" + hash + ":trash"
+	v1, err := NewEncrypted(backend, secrets, 1, func(key string) string { return "app:v1:" + hash + ":" })
+	require.NoError(t, err)
+	require.NoError(t, v1.Set(ctx, previous, []byte("entries"), WithExpire(time.Hour)))
+
+	c, err := NewEncrypted(backend, secrets, 1, keys.Scope)
+	require.NoError(t, err)
+	keys.WithPrevious(c, 1)
+	require.Equal(t, "app:v1:", keys.PreviousRoot())
+	require.Equal(t, "app:v1:"+hash+":", keys.Scope(previous))
+	require.Equal(t, keys.Prefix(token), keys.Scope(keys.Key(ctx, token, "favorites")))
+
+	key := keys.Key(ctx, token, "trash")
+	got, err := c.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("entries"), got)
+	require.False(t, backend.Has(ctx, previous), "previous key should be removed")
+
+	ttl, err := c.TTL(ctx, key)
+	require.NoError(t, err)
+	require.InDelta(t, time.Hour, ttl, float64(time.Second), "expiration should be kept")
+}
+
+func TestKeyBuilderLegacyRetry(t *testing.T) {
+	ctx := context.Background()
+	token := "1234-abcd-access-token"
+	s := miniredis.RunT(t)
+	c := NewRedis(redis.NewClient(&redis.Options{Addr: s.Addr()}))
+
+	legacy := func(identity string, name string) string { return fmt.Sprintf("%s/%s", identity, name) }
+	require.NoError(t, c.Set(ctx, "1234-abcd-access-token/favorites", []byte("articles")))
+
+	keys := NewKeyBuilder("app", 1, "secret").WithLegacy(c, legacy)
+
+	s.SetError("LOADING")
+	key := keys.Key(ctx, token, "favorites")
+	s.SetError("")
+	require.False(t, c.Has(ctx, key), "migration failed")
+
+	// failed migration is tried again
+	require.Equal(t, key, keys.Key(ctx, token, "favorites"))
+	got, err := c.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("articles"), got)
+	require.False(t, c.Has(ctx, "1234-abcd-access-token/favorites"))
+}
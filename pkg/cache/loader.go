@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// loadGroup coalesce loaders for the same key, so only one load per key is in flight
+type loadGroup struct {
+	group singleflight.Group
+}
+
+func (g *loadGroup) getOrLoad(ctx context.Context, c Interface, key string, loader LoaderFunc, opts []setOption) ([]byte, error) {
+	option := applySetOptions(opts)
+
+	data, err := c.Get(ctx, key)
+	if err == nil {
+		value, refreshAt := splitRefreshAt(data)
+		if option.softExpire != 0 && refreshAt.Before(time.Now()) {
+			// serve stale value and refresh it in background
+			g.group.DoChan(key, g.load(ctx, c, key, loader, option, opts))
+		}
+		return value, nil
+	}
+
+	if err != ErrNotExists {
+		return nil, err
+	}
+
+	ch := g.group.DoChan(key, g.load(ctx, c, key, loader, option, opts))
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
+	case r := <-ch:
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return r.Val.([]byte), nil
+	}
+}
+
+func (g *loadGroup) load(ctx context.Context, c Interface, key string, loader LoaderFunc, option *setOptions, opts []setOption) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		// loader is shared with other callers, so do not cancel it with this caller
+		ctx := context.WithoutCancel(ctx)
+
+		data, err := loader(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		stored := data
+		if option.softExpire != 0 {
+			stored = withRefreshAt(data, time.Now().Add(option.softExpire))
+		}
+
+		if err := c.Set(ctx, key, stored, opts...); err != nil {
+			return nil, err
+		}
+
+		return data, nil
+	}
+}
+
+// header of value stored with soft expire, followed by refresh time in unix nano
+// refresh time is kept in the value, so it is written and expired with the value atomically
+// json, gob and msgpack never start with the header byte
+const (
+	refreshHeader     = 0xc1
+	refreshHeaderSize = 9
+)
+
+// withRefreshAt return value prefixed with refresh time
+func withRefreshAt(value []byte, refreshAt time.Time) []byte {
+	data := make([]byte, refreshHeaderSize, refreshHeaderSize+len(value))
+	data[0] = refreshHeader
+	binary.BigEndian.PutUint64(data[1:], uint64(refreshAt.UnixNano()))
+	return append(data, value...)
+}
+
+// splitRefreshAt return value and its refresh time, refresh time is zero if value was stored without soft expire
+func splitRefreshAt(data []byte) ([]byte, time.Time) {
+	if len(data) < refreshHeaderSize || data[0] != refreshHeader {
+		return data, time.Time{}
+	}
+
+	return data[refreshHeaderSize:], time.Unix(0, int64(binary.BigEndian.Uint64(data[1:refreshHeaderSize])))
+}
@@ -0,0 +1,401 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	ErrLocked   = errors.New("locked")
+	ErrLockLost = errors.New("lock lost")
+)
+
+// lockRetryInterval wait before retry to acquire lock held by others
+const lockRetryInterval = 100 * time.Millisecond
+
+// Locker acquire leases on names, so work is done by only one holder at a time
+type Locker interface {
+	// acquire lock of name for ttl, return ErrLocked if it is held by others
+	TryLock(ctx context.Context, name string, ttl time.Duration) (*Lock, error)
+
+	// wait until lock of name acquired or ctx done
+	Lock(ctx context.Context, name string, ttl time.Duration) (*Lock, error)
+
+	// return true if lease of name is held by anyone
+	Held(ctx context.Context, name string) (bool, error)
+}
+
+// lockBackend store of leases
+type lockBackend interface {
+	// acquire lease of name for owner, return fencing token or 0 if held by others
+	acquire(ctx context.Context, name string, owner string, ttl time.Duration) (int64, error)
+
+	// extend lease of owner, return false if lease is not held by owner
+	renew(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error)
+
+	// release lease of owner
+	release(ctx context.Context, name string, owner string) error
+
+	// return true if lease of name is held
+	held(ctx context.Context, name string) (bool, error)
+}
+
+type lockerImpl struct {
+	backend lockBackend
+}
+
+var _ Locker = (*lockerImpl)(nil)
+
+func (l *lockerImpl) TryLock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	owner := make([]byte, 16)
+	if _, err := rand.Read(owner); err != nil {
+		return nil, err
+	}
+
+	lock := &Lock{
+		backend: l.backend,
+		name:    name,
+		owner:   hex.EncodeToString(owner),
+		ttl:     ttl,
+	}
+
+	token, err := l.backend.acquire(ctx, lock.name, lock.owner, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	if token == 0 {
+		return nil, ErrLocked
+	}
+	lock.token = token
+
+	return lock, nil
+}
+
+func (l *lockerImpl) Lock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		lock, err := l.TryLock(ctx, name, ttl)
+		if err != ErrLocked {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *lockerImpl) Held(ctx context.Context, name string) (bool, error) {
+	return l.backend.held(ctx, name)
+}
+
+// Lock lease of name, the lease expires after ttl unless it is refreshed
+type Lock struct {
+	backend lockBackend
+	name    string
+	owner   string
+	ttl     time.Duration
+	token   int64
+}
+
+// Token return fencing token, which increases whenever the lock of name is acquired
+// pass it to storage to reject writes of holders whose lease already expired
+func (l *Lock) Token() int64 { return l.token }
+
+// Refresh extend lease for ttl, return ErrLockLost if lease expired and is taken by others
+func (l *Lock) Refresh(ctx context.Context) error {
+	ok, err := l.backend.renew(ctx, l.name, l.owner, l.ttl)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Unlock release lease, lease of others are not touched
+func (l *Lock) Unlock(ctx context.Context) error {
+	return l.backend.release(ctx, l.name, l.owner)
+}
+
+// KeepAlive refresh lease every third of ttl until returned context is canceled
+// returned context is canceled when lease is lost, so work under the lock stops
+func (l *Lock) KeepAlive(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	go func() {
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if err := l.Refresh(ctx); err != nil && ctx.Err() == nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// NewLocalLocker return locker for a single process, e.g. with bigcache
+func NewLocalLocker() Locker {
+	return &lockerImpl{
+		backend: &localLockBackend{
+			leases: make(map[string]*localLease),
+			tokens: make(map[string]int64),
+		},
+	}
+}
+
+type localLease struct {
+	owner    string
+	expireAt time.Time
+}
+
+type localLockBackend struct {
+	mu     sync.Mutex
+	leases map[string]*localLease
+	tokens map[string]int64 // last fencing token of name
+}
+
+func (b *localLockBackend) acquire(ctx context.Context, name string, owner string, ttl time.Duration) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if lease, ok := b.leases[name]; ok && lease.expireAt.After(now) {
+		return 0, nil
+	}
+
+	// tokens start from clock, so they increase over tokens stored before restart, e.g. in disk cache
+	b.leases[name] = &localLease{owner: owner, expireAt: now.Add(ttl)}
+	b.tokens[name] = max(b.tokens[name]+1, now.UnixNano())
+	return b.tokens[name], nil
+}
+
+func (b *localLockBackend) renew(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	lease, ok := b.leases[name]
+	if !ok || lease.owner != owner || !lease.expireAt.After(now) {
+		return false, nil
+	}
+
+	lease.expireAt = now.Add(ttl)
+	return true, nil
+}
+
+func (b *localLockBackend) release(ctx context.Context, name string, owner string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if lease, ok := b.leases[name]; ok && lease.owner == owner {
+		delete(b.leases, name)
+	}
+	return nil
+}
+
+func (b *localLockBackend) held(ctx context.Context, name string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	lease, ok := b.leases[name]
+	return ok && lease.expireAt.After(time.Now()), nil
+}
+
+// redisLockTokenTTL fencing counter of lock is removed if the lock is not acquired for this long
+const redisLockTokenTTL = 30 * 24 * time.Hour
+
+// NewRedisLocker return locker shared by all processes using r
+// leases are set with SET NX PX and fencing tokens are counted by INCR
+// counter starts from clock in milliseconds, which fits in number of lua, if it is not set or expired, so tokens still increase
+func NewRedisLocker(r redis.UniversalClient) Locker {
+	return &lockerImpl{backend: &redisLockBackend{client: r}}
+}
+
+// lock key and its fencing counter, hash tagged to be in the same slot
+func redisLockKeys(name string) []string {
+	return []string{"lock:{" + name + "}", "lock:{" + name + "}:token"}
+}
+
+var (
+	redisLockAcquire = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	redis.call("SET", KEYS[2], ARGV[3], "NX")
+	local token = redis.call("INCR", KEYS[2])
+	redis.call("PEXPIRE", KEYS[2], ARGV[4])
+	return token
+end
+return 0`)
+
+	redisLockRenew = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	redisLockRelease = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+type redisLockBackend struct {
+	client redis.UniversalClient
+}
+
+func (b *redisLockBackend) acquire(ctx context.Context, name string, owner string, ttl time.Duration) (int64, error) {
+	return redisLockAcquire.Run(ctx, b.client, redisLockKeys(name), owner, ttl.Milliseconds(), time.Now().UnixMilli(), redisLockTokenTTL.Milliseconds()).Int64()
+}
+
+func (b *redisLockBackend) renew(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	renewed, err := redisLockRenew.Run(ctx, b.client, redisLockKeys(name)[:1], owner, ttl.Milliseconds()).Int64()
+	return renewed == 1, err
+}
+
+func (b *redisLockBackend) release(ctx context.Context, name string, owner string) error {
+	return redisLockRelease.Run(ctx, b.client, redisLockKeys(name)[:1], owner).Err()
+}
+
+func (b *redisLockBackend) held(ctx context.Context, name string) (bool, error) {
+	n, err := b.client.Exists(ctx, redisLockKeys(name)[0]).Result()
+	return n == 1, err
+}
+
+// NewMemcacheLocker return locker shared by all processes using client
+// leases are set with add and released or renewed with cas, fencing tokens are counted by incr
+// lease ttl is rounded up to seconds
+func NewMemcacheLocker(client *memcache.Client) Locker {
+	return &lockerImpl{backend: &memcacheLockBackend{client: client}}
+}
+
+type memcacheLockBackend struct {
+	client *memcache.Client
+}
+
+// lock key and its fencing counter
+func memcacheLockKeys(name string) (string, string) {
+	return memcacheKey("lock:" + name), memcacheKey("lock:" + name + ":token")
+}
+
+func memcacheLockExpiration(ttl time.Duration) int32 {
+	return memcacheExpiration(time.Now().Add(ttl))
+}
+
+func (b *memcacheLockBackend) acquire(ctx context.Context, name string, owner string, ttl time.Duration) (int64, error) {
+	key, tokenKey := memcacheLockKeys(name)
+	if err := b.client.Add(&memcache.Item{Key: key, Value: []byte(owner), Expiration: memcacheLockExpiration(ttl)}); err != nil {
+		if err == memcache.ErrNotStored {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	token, err := b.nextToken(tokenKey)
+	if err != nil {
+		b.release(ctx, name, owner)
+		return 0, err
+	}
+	return token, nil
+}
+
+// nextToken increase fencing counter
+// counter starts from clock if it is not set or evicted, so tokens still increase
+func (b *memcacheLockBackend) nextToken(key string) (int64, error) {
+	for {
+		token, err := b.client.Increment(key, 1)
+		if err == nil {
+			return int64(token), nil
+		}
+		if err != memcache.ErrCacheMiss {
+			return 0, err
+		}
+
+		if err := b.client.Add(&memcache.Item{Key: key, Value: []byte(strconv.FormatInt(time.Now().UnixNano(), 10))}); err != nil && err != memcache.ErrNotStored {
+			return 0, err
+		}
+	}
+}
+
+// lease return lease item of owner, nil if lease is held by others or expired
+func (b *memcacheLockBackend) lease(name string, owner string) (*memcache.Item, error) {
+	key, _ := memcacheLockKeys(name)
+	item, err := b.client.Get(key)
+	if err != nil {
+		if err == memcache.ErrCacheMiss {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if string(item.Value) != owner {
+		return nil, nil
+	}
+	return item, nil
+}
+
+func (b *memcacheLockBackend) renew(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	item, err := b.lease(name, owner)
+	if err != nil || item == nil {
+		return false, err
+	}
+
+	item.Expiration = memcacheLockExpiration(ttl)
+	if err := b.client.CompareAndSwap(item); err != nil {
+		if err == memcache.ErrCASConflict || err == memcache.ErrCacheMiss || err == memcache.ErrNotStored {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// release expire lease with cas, so lease taken by others after it expired is not deleted
+func (b *memcacheLockBackend) release(ctx context.Context, name string, owner string) error {
+	item, err := b.lease(name, owner)
+	if err != nil || item == nil {
+		return err
+	}
+
+	item.Expiration = -1
+	if err := b.client.CompareAndSwap(item); err != nil && err != memcache.ErrCASConflict && err != memcache.ErrCacheMiss && err != memcache.ErrNotStored {
+		return err
+	}
+	return nil
+}
+
+func (b *memcacheLockBackend) held(ctx context.Context, name string) (bool, error) {
+	key, _ := memcacheLockKeys(name)
+	if _, err := b.client.Get(key); err != nil {
+		if err == memcache.ErrCacheMiss {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
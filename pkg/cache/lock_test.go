@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocker(t *testing.T) {
+	type args struct {
+		newLocker func(t *testing.T) Locker
+	}
+	tests := [...]struct {
+		name string
+		args args
+	}{
+		{"local", args{func(t *testing.T) Locker { return NewLocalLocker() }}},
+		{"redis", args{func(t *testing.T) Locker { return NewRedisLocker(newTestRedis(t)) }}},
+		{"redis cluster", args{func(t *testing.T) Locker { return NewRedisLocker(newTestRedisCluster(t)) }}},
+		{"memcache", args{func(t *testing.T) Locker {
+			client, _ := newTestMemcache(t)
+			return NewMemcacheLocker(client)
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			locker := tt.args.newLocker(t)
+
+			held, err := locker.Held(ctx, "job")
+			require.NoError(t, err)
+			require.False(t, held)
+
+			lock, err := locker.TryLock(ctx, "job", time.Second)
+			require.NoError(t, err)
+			require.Positive(t, lock.Token())
+
+			held, err = locker.Held(ctx, "job")
+			require.NoError(t, err)
+			require.True(t, held)
+
+			_, err = locker.TryLock(ctx, "job", time.Second)
+			require.ErrorIs(t, err, ErrLocked)
+
+			other, err := locker.TryLock(ctx, "other-job", time.Second)
+			require.NoError(t, err, "locks of other names are independent")
+			require.NoError(t, other.Unlock(ctx))
+
+			require.NoError(t, lock.Refresh(ctx))
+			require.NoError(t, lock.Unlock(ctx))
+
+			next, err := locker.TryLock(ctx, "job", time.Second)
+			require.NoError(t, err)
+			require.Greater(t, next.Token(), lock.Token(), "fencing token should increase")
+
+			require.ErrorIs(t, lock.Refresh(ctx), ErrLockLost, "released lease should not be renewed")
+			require.NoError(t, lock.Unlock(ctx), "released lease should not release lease of others")
+			_, err = locker.TryLock(ctx, "job", time.Second)
+			require.ErrorIs(t, err, ErrLocked)
+
+			// wait for lock
+			waitCtx, cancel := context.WithTimeout(ctx, lockRetryInterval)
+			defer cancel()
+			_, err = locker.Lock(waitCtx, "job", time.Second)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+
+			go func() {
+				time.Sleep(lockRetryInterval)
+				next.Unlock(ctx)
+			}()
+			waited, err := locker.Lock(ctx, "job", time.Second)
+			require.NoError(t, err)
+			require.NoError(t, waited.Unlock(ctx))
+		})
+	}
+}
+
+func TestLockExpire(t *testing.T) {
+	ctx := context.Background()
+	locker := NewLocalLocker()
+
+	lock, err := locker.TryLock(ctx, "job", 50*time.Millisecond)
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	next, err := locker.TryLock(ctx, "job", time.Second)
+	require.NoError(t, err, "expired lease should be taken")
+	require.ErrorIs(t, lock.Refresh(ctx), ErrLockLost)
+
+	// lost lease cancels work under the lock
+	workCtx, cancel := lock.KeepAlive(ctx)
+	defer cancel()
+	select {
+	case <-workCtx.Done():
+	case <-time.After(time.Second):
+		require.Fail(t, "context should be canceled when lease lost")
+	}
+	require.NoError(t, next.Unlock(ctx))
+}
+
+func TestLockKeepAlive(t *testing.T) {
+	ctx := context.Background()
+	locker := NewRedisLocker(newTestRedis(t))
+
+	lock, err := locker.TryLock(ctx, "job", 100*time.Millisecond)
+	require.NoError(t, err)
+
+	workCtx, cancel := lock.KeepAlive(ctx)
+	defer cancel()
+
+	// miniredis does not expire keys by wall clock, check with renewal of lease instead
+	time.Sleep(250 * time.Millisecond)
+	require.NoError(t, workCtx.Err())
+	require.NoError(t, lock.Refresh(ctx))
+	cancel()
+	require.NoError(t, lock.Unlock(ctx))
+}
+
+func TestLockExclusive(t *testing.T) {
+	ctx := context.Background()
+	locker := NewRedisLocker(newTestRedis(t))
+
+	var running, acquired atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			lock, err := locker.TryLock(ctx, "job", time.Second)
+			if err == ErrLocked {
+				return
+			}
+			require.NoError(t, err)
+			defer lock.Unlock(ctx)
+
+			acquired.Add(1)
+			require.Equal(t, int32(1), running.Add(1), "only one holder should run")
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	require.NotZero(t, acquired.Load())
+}
+
+func TestRedisLockToken(t *testing.T) {
+	ctx := context.Background()
+	s := miniredis.RunT(t)
+	locker := NewRedisLocker(redis.NewClient(&redis.Options{Addr: s.Addr()}))
+
+	lock, err := locker.TryLock(ctx, "job", time.Second)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock(ctx))
+
+	tokenKey := redisLockKeys("job")[1]
+	require.Equal(t, redisLockTokenTTL, s.TTL(tokenKey), "fencing counter should expire")
+
+	s.FastForward(redisLockTokenTTL)
+	require.False(t, s.Exists(tokenKey))
+	time.Sleep(2 * time.Millisecond) // expired counter is seeded from clock, which miniredis does not fast forward
+
+	next, err := locker.TryLock(ctx, "job", time.Second)
+	require.NoError(t, err)
+	require.Greater(t, next.Token(), lock.Token(), "fencing token should increase after counter expired")
+}
+
+func TestMemcacheLocker(t *testing.T) {
+	ctx := context.Background()
+	client, server := newTestMemcache(t)
+	locker := NewMemcacheLocker(client)
+	other := NewMemcacheLocker(client) // locker of other replica
+
+	lock, err := locker.TryLock(ctx, "job", time.Second)
+	require.NoError(t, err)
+	_, err = other.TryLock(ctx, "job", time.Second)
+	require.ErrorIs(t, err, ErrLocked, "lease should be shared by lockers")
+	require.NoError(t, lock.Unlock(ctx))
+
+	// fencing counter is evicted
+	server.evict(":token")
+	next, err := other.TryLock(ctx, "job", time.Second)
+	require.NoError(t, err)
+	require.Greater(t, next.Token(), lock.Token(), "fencing token should increase after eviction")
+	require.NoError(t, next.Unlock(ctx))
+}
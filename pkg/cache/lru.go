@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU in-process map bounded by size, least recently used entry is evicted when it is full
+// entries expire after ttl if ttl is not 0; safe for concurrent use
+type LRU[K comparable, V any] struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List // front is most recently used
+	entries map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key      K
+	value    V
+	expireAt time.Time
+}
+
+// NewLRU return LRU which keeps at most size entries for ttl
+func NewLRU[K comparable, V any](size int, ttl time.Duration) *LRU[K, V] {
+	if size <= 0 {
+		panic("lru size should be positive")
+	}
+
+	return &LRU[K, V]{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[K]*list.Element, size),
+	}
+}
+
+// Get return value of key, false if not exists or expired
+func (l *LRU[K, V]) Get(key K) (V, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	entry := elem.Value.(*lruEntry[K, V])
+	if l.expired(entry) {
+		l.remove(elem)
+		var zero V
+		return zero, false
+	}
+
+	l.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Add set value of key, evict least recently used entry if full
+func (l *LRU[K, V]) Add(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var expireAt time.Time
+	if l.ttl != 0 {
+		expireAt = time.Now().Add(l.ttl)
+	}
+
+	if elem, ok := l.entries[key]; ok {
+		entry := elem.Value.(*lruEntry[K, V])
+		entry.value, entry.expireAt = value, expireAt
+		l.order.MoveToFront(elem)
+		return
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry[K, V]{key: key, value: value, expireAt: expireAt})
+	if l.order.Len() > l.size {
+		l.remove(l.order.Back())
+	}
+}
+
+// Remove delete key
+func (l *LRU[K, V]) Remove(key K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.entries[key]; ok {
+		l.remove(elem)
+	}
+}
+
+// Purge delete all entries
+func (l *LRU[K, V]) Purge() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.order.Init()
+	clear(l.entries)
+}
+
+// Len return number of entries including expired ones not evicted yet
+func (l *LRU[K, V]) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.order.Len()
+}
+
+func (l *LRU[K, V]) expired(entry *lruEntry[K, V]) bool {
+	return !entry.expireAt.IsZero() && entry.expireAt.Before(time.Now())
+}
+
+func (l *LRU[K, V]) remove(elem *list.Element) {
+	l.order.Remove(elem)
+	delete(l.entries, elem.Value.(*lruEntry[K, V]).key)
+}
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	l := NewLRU[string, int](2, 0)
+
+	l.Add("a", 1)
+	l.Add("b", 2)
+	_, ok := l.Get("a")
+	require.True(t, ok)
+
+	// b is least recently used
+	l.Add("c", 3)
+	require.Equal(t, 2, l.Len())
+	_, ok = l.Get("b")
+	require.False(t, ok, "least recently used entry should be evicted")
+
+	got, ok := l.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, got)
+
+	l.Add("a", 10)
+	got, _ = l.Get("a")
+	require.Equal(t, 10, got)
+	require.Equal(t, 2, l.Len())
+
+	l.Remove("a")
+	_, ok = l.Get("a")
+	require.False(t, ok)
+
+	l.Purge()
+	require.Zero(t, l.Len())
+}
+
+func TestLRUExpire(t *testing.T) {
+	l := NewLRU[string, int](10, 50*time.Millisecond)
+
+	l.Add("a", 1)
+	_, ok := l.Get("a")
+	require.True(t, ok)
+
+	time.Sleep(100 * time.Millisecond)
+	_, ok = l.Get("a")
+	require.False(t, ok, "expired entry")
+	require.Zero(t, l.Len(), "expired entry is removed when read")
+}
@@ -0,0 +1,348 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/pkg/errors"
+	"golang.org/x/exp/maps"
+)
+
+// memcache entry header: version, expire at in unix nano, number of chunks, generation of chunks
+// values larger than memcacheChunkSize are split into chunk items and the entry has only the header
+const (
+	memcacheVersion    = 1
+	memcacheHeaderSize = 21
+
+	memcacheChunkSize   = 1000 << 10 // under 1MB item limit with key and header
+	memcacheMaxKeyLen   = 250
+	memcacheRelativeMax = 30 * 24 * time.Hour // memcached takes longer expiration as unix time
+)
+
+// NewMemcache return cache stored in memcached
+// memcached can not list keys, so Keys return ErrNotSupported
+func NewMemcache(client *memcache.Client) Interface {
+	return &memcacheCacheImpl{
+		client: client,
+	}
+}
+
+type memcacheCacheImpl struct {
+	client *memcache.Client
+	loads  loadGroup
+}
+
+var _ Interface = (*memcacheCacheImpl)(nil)
+
+// memcacheKey return key for memcached; keys which are too long or have spaces or control characters are hashed
+func memcacheKey(key string) string {
+	legal := len(key) <= memcacheMaxKeyLen
+	for i := 0; legal && i < len(key); i++ {
+		legal = key[i] > ' ' && key[i] != 0x7f
+	}
+
+	if legal {
+		return key
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// memcacheChunkKey return key of chunk i of the entry generation
+func memcacheChunkKey(key string, gen uint64, i int) string {
+	return memcacheKey(fmt.Sprintf("%s/chunk/%x/%d", key, gen, i))
+}
+
+// memcacheExpiration return expiration of memcached item; seconds or unix time if longer than 30 days
+func memcacheExpiration(expireAt time.Time) int32 {
+	if expireAt.IsZero() {
+		return 0
+	}
+
+	ttl := time.Until(expireAt)
+	if ttl > memcacheRelativeMax {
+		return int32(expireAt.Unix() + 1)
+	}
+
+	// round up, so item lives until expireAt; header is checked for exact expiration
+	return int32(max((ttl+time.Second-1)/time.Second, 1))
+}
+
+type memcacheHeader struct {
+	expireAt time.Time
+	chunks   uint32
+	gen      uint64
+}
+
+func parseMemcacheEntry(key string, value []byte) (*memcacheHeader, []byte, error) {
+	if len(value) < memcacheHeaderSize || value[0] != memcacheVersion {
+		return nil, nil, errors.Errorf("invalid entry: %s", key)
+	}
+
+	header := &memcacheHeader{
+		chunks: binary.BigEndian.Uint32(value[9:]),
+		gen:    binary.BigEndian.Uint64(value[13:]),
+	}
+	if nsec := int64(binary.BigEndian.Uint64(value[1:])); nsec != 0 {
+		header.expireAt = time.Unix(0, nsec)
+	}
+
+	return header, value[memcacheHeaderSize:], nil
+}
+
+func (h *memcacheHeader) expired(now time.Time) bool {
+	return !h.expireAt.IsZero() && h.expireAt.Before(now)
+}
+
+func (h *memcacheHeader) chunkKeys(key string) []string {
+	keys := make([]string, h.chunks)
+	for i := range keys {
+		keys[i] = memcacheChunkKey(key, h.gen, i)
+	}
+	return keys
+}
+
+// items return items to store value, entry item comes last so readers never see entry without its chunks
+func (m *memcacheCacheImpl) items(key string, value []byte, expireAt time.Time) ([]*memcache.Item, error) {
+	header := make([]byte, memcacheHeaderSize)
+	header[0] = memcacheVersion
+	if !expireAt.IsZero() {
+		binary.BigEndian.PutUint64(header[1:], uint64(expireAt.UnixNano()))
+	}
+	expiration := memcacheExpiration(expireAt)
+
+	if len(value) <= memcacheChunkSize {
+		return []*memcache.Item{{Key: memcacheKey(key), Value: append(header, value...), Expiration: expiration}}, nil
+	}
+
+	var gen [8]byte
+	if _, err := rand.Read(gen[:]); err != nil {
+		return nil, err
+	}
+
+	h := &memcacheHeader{chunks: uint32((len(value) + memcacheChunkSize - 1) / memcacheChunkSize), gen: binary.BigEndian.Uint64(gen[:])}
+	binary.BigEndian.PutUint32(header[9:], h.chunks)
+	copy(header[13:], gen[:])
+
+	items := make([]*memcache.Item, 0, h.chunks+1)
+	for i, chunkKey := range h.chunkKeys(key) {
+		chunk := value[i*memcacheChunkSize : min((i+1)*memcacheChunkSize, len(value))]
+		items = append(items, &memcache.Item{Key: chunkKey, Value: chunk, Expiration: expiration})
+	}
+	return append(items, &memcache.Item{Key: memcacheKey(key), Value: header, Expiration: expiration}), nil
+}
+
+func (m *memcacheCacheImpl) Set(ctx context.Context, key string, value []byte, opts ...setOption) error {
+	return m.MSet(ctx, map[string][]byte{key: value}, opts...)
+}
+
+func (m *memcacheCacheImpl) MSet(ctx context.Context, values map[string][]byte, opts ...setOption) error {
+	option := applySetOptions(opts)
+
+	var expireAt time.Time
+	if option.expire != 0 {
+		expireAt = time.Now().Add(option.expire)
+	}
+
+	// chunks of overwritten entries are deleted after new entries are set
+	previous, _, err := m.entries(maps.Keys(values))
+	if err != nil {
+		return err
+	}
+
+	for key, value := range values {
+		items, err := m.items(key, value, expireAt)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			if err := m.client.Set(item); err != nil {
+				return errors.Wrapf(err, "set failed: %s", key)
+			}
+		}
+
+		if header, ok := previous[key]; ok {
+			if err := m.deleteItems(header.chunkKeys(key)); err != nil {
+				return errors.Wrapf(err, "delete chunks failed: %s", key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// deleteItems delete memcached items, missing items are ignored
+func (m *memcacheCacheImpl) deleteItems(mkeys []string) error {
+	for _, mkey := range mkeys {
+		if err := m.client.Delete(mkey); err != nil && err != memcache.ErrCacheMiss {
+			return err
+		}
+	}
+	return nil
+}
+
+// entries return headers and values of existing keys, values of chunked entries are not loaded
+func (m *memcacheCacheImpl) entries(keys []string) (map[string]*memcacheHeader, map[string][]byte, error) {
+	mkeys := make([]string, len(keys))
+	for i, key := range keys {
+		mkeys[i] = memcacheKey(key)
+	}
+
+	items, err := m.client.GetMulti(mkeys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	headers := make(map[string]*memcacheHeader, len(items))
+	values := make(map[string][]byte, len(items))
+	for i, key := range keys {
+		item, ok := items[mkeys[i]]
+		if !ok {
+			continue
+		}
+
+		header, value, err := parseMemcacheEntry(key, item.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if header.expired(now) {
+			continue
+		}
+
+		headers[key] = header
+		values[key] = value
+	}
+
+	return headers, values, nil
+}
+
+func (m *memcacheCacheImpl) Get(ctx context.Context, key string) ([]byte, error) {
+	values, err := m.MGet(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if values[0] == nil {
+		return nil, ErrNotExists
+	}
+	return values[0], nil
+}
+
+func (m *memcacheCacheImpl) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	headers, values, err := m.entries(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// load chunks of all chunked entries at once
+	var chunkKeys []string
+	for key, header := range headers {
+		if header.chunks > 0 {
+			chunkKeys = append(chunkKeys, header.chunkKeys(key)...)
+		}
+	}
+
+	var chunks map[string]*memcache.Item
+	if len(chunkKeys) > 0 {
+		if chunks, err = m.client.GetMulti(chunkKeys); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([][]byte, len(keys))
+	for i, key := range keys {
+		header, ok := headers[key]
+		if !ok {
+			continue
+		}
+
+		if header.chunks == 0 {
+			result[i] = values[key]
+			continue
+		}
+
+		// entry whose chunks are evicted is treated as not exists
+		var value []byte
+		for _, chunkKey := range header.chunkKeys(key) {
+			chunk, ok := chunks[chunkKey]
+			if !ok {
+				value = nil
+				break
+			}
+			value = append(value, chunk.Value...)
+		}
+		result[i] = value
+	}
+
+	return result, nil
+}
+
+// Has read only the entry item, chunks are not checked
+func (m *memcacheCacheImpl) Has(ctx context.Context, key string) bool {
+	headers, _, err := m.entries([]string{key})
+	if err != nil {
+		return false
+	}
+
+	_, ok := headers[key]
+	return ok
+}
+
+func (m *memcacheCacheImpl) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	headers, _, err := m.entries(keys)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		var mkeys []string
+		if header, ok := headers[key]; ok {
+			mkeys = header.chunkKeys(key)
+		}
+
+		if err := m.deleteItems(append([]string{memcacheKey(key)}, mkeys...)); err != nil {
+			return errors.Wrapf(err, "delete failed: %s", key)
+		}
+	}
+
+	return nil
+}
+
+func (m *memcacheCacheImpl) TTL(ctx context.Context, key string) (time.Duration, error) {
+	headers, _, err := m.entries([]string{key})
+	if err != nil {
+		return 0, err
+	}
+
+	header, ok := headers[key]
+	if !ok {
+		return 0, ErrNotExists
+	}
+
+	if header.expireAt.IsZero() {
+		return 0, nil
+	}
+	return time.Until(header.expireAt), nil
+}
+
+func (m *memcacheCacheImpl) Keys(ctx context.Context, prefix string) ([]string, error) {
+	return nil, ErrNotSupported
+}
+
+func (m *memcacheCacheImpl) GetOrLoad(ctx context.Context, key string, loader LoaderFunc, opts ...setOption) ([]byte, error) {
+	return m.loads.getOrLoad(ctx, m, key, loader, opts)
+}
@@ -0,0 +1,308 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/require"
+)
+
+// testMemcached in-process memcached which speaks enough text protocol for gomemcache
+type testMemcached struct {
+	mu    sync.Mutex
+	items map[string]*testMemcachedItem
+	cas   uint64 // last cas unique
+}
+
+type testMemcachedItem struct {
+	flags    uint32
+	value    []byte
+	expireAt time.Time
+	cas      uint64
+}
+
+// testMemcachedMaxItemSize item size limit of memcached
+const testMemcachedMaxItemSize = 1 << 20
+
+// newTestMemcache return client of new in-process memcached, not shared with other tests
+func newTestMemcache(t *testing.T) (*memcache.Client, *testMemcached) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	s := &testMemcached{items: make(map[string]*testMemcachedItem)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+
+	return memcache.New(ln.Addr().String()), s
+}
+
+func (s *testMemcached) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		var resp bytes.Buffer
+		switch fields[0] {
+		case "get", "gets":
+			s.get(&resp, fields[1:])
+
+		case "set", "add", "cas":
+			size, _ := strconv.Atoi(fields[4])
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return
+			}
+			s.set(&resp, fields[0], fields[1:], data[:size])
+
+		case "incr":
+			s.incr(&resp, fields[1], fields[2])
+
+		case "delete":
+			s.mu.Lock()
+			if _, ok := s.item(fields[1]); ok {
+				delete(s.items, fields[1])
+				resp.WriteString("DELETED\r\n")
+			} else {
+				resp.WriteString("NOT_FOUND\r\n")
+			}
+			s.mu.Unlock()
+
+		case "version":
+			resp.WriteString("VERSION test\r\n")
+
+		default:
+			resp.WriteString("ERROR\r\n")
+		}
+
+		if _, err := conn.Write(resp.Bytes()); err != nil {
+			return
+		}
+	}
+}
+
+// item return item of key which is not expired, should be called with lock held
+func (s *testMemcached) item(key string) (*testMemcachedItem, bool) {
+	item, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !item.expireAt.IsZero() && !item.expireAt.After(time.Now()) {
+		delete(s.items, key)
+		return nil, false
+	}
+	return item, true
+}
+
+func (s *testMemcached) get(w io.Writer, keys []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		if item, ok := s.item(key); ok {
+			fmt.Fprintf(w, "VALUE %s %d %d %d\r\n%s\r\n", key, item.flags, len(item.value), item.cas, item.value)
+		}
+	}
+	io.WriteString(w, "END\r\n")
+}
+
+// set store value by set, add or cas; args are key, flags, exptime, bytes and cas unique of cas
+func (s *testMemcached) set(w io.Writer, verb string, args []string, value []byte) {
+	if len(value) > testMemcachedMaxItemSize {
+		io.WriteString(w, "SERVER_ERROR object too large for cache\r\n")
+		return
+	}
+
+	key := args[0]
+	f, _ := strconv.ParseUint(args[1], 10, 32)
+	item := &testMemcachedItem{flags: uint32(f), value: value}
+
+	// relative seconds up to 30 days, unix time after that, negative is expired immediately
+	switch exp, _ := strconv.ParseInt(args[2], 10, 64); {
+	case exp > int64(memcacheRelativeMax/time.Second):
+		item.expireAt = time.Unix(exp, 0)
+	case exp > 0:
+		item.expireAt = time.Now().Add(time.Duration(exp) * time.Second)
+	case exp < 0:
+		item.expireAt = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, exists := s.item(key)
+	switch verb {
+	case "add":
+		if exists {
+			io.WriteString(w, "NOT_STORED\r\n")
+			return
+		}
+	case "cas":
+		if !exists {
+			io.WriteString(w, "NOT_FOUND\r\n")
+			return
+		}
+		if cas, _ := strconv.ParseUint(args[4], 10, 64); cas != old.cas {
+			io.WriteString(w, "EXISTS\r\n")
+			return
+		}
+	}
+
+	s.cas++
+	item.cas = s.cas
+	s.items[key] = item
+
+	io.WriteString(w, "STORED\r\n")
+}
+
+func (s *testMemcached) incr(w io.Writer, key string, delta string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.item(key)
+	if !ok {
+		io.WriteString(w, "NOT_FOUND\r\n")
+		return
+	}
+
+	value, err := strconv.ParseUint(string(item.value), 10, 64)
+	if err != nil {
+		io.WriteString(w, "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n")
+		return
+	}
+
+	d, _ := strconv.ParseUint(delta, 10, 64)
+	s.cas++
+	item.value, item.cas = []byte(strconv.FormatUint(value+d, 10)), s.cas
+	fmt.Fprintf(w, "%d\r\n", value+d)
+}
+
+// evict remove items whose key contains substr, as memcached evicts items under memory pressure
+func (s *testMemcached) evict(substr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.items {
+		if strings.Contains(key, substr) {
+			delete(s.items, key)
+		}
+	}
+}
+
+func (s *testMemcached) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+func TestMemcacheChunk(t *testing.T) {
+	ctx := context.Background()
+	client, server := newTestMemcache(t)
+	cache := NewMemcache(client)
+
+	large := bytes.Repeat([]byte("0123456789"), 300<<10) // 3MB, over item limit
+	require.NoError(t, cache.Set(ctx, "large", large, WithExpire(time.Hour)))
+	require.NoError(t, cache.Set(ctx, "small", []byte("value")))
+	require.Equal(t, 5, server.len(), "large value should be split into chunks")
+
+	got, err := cache.Get(ctx, "large")
+	require.NoError(t, err)
+	require.Equal(t, large, got)
+
+	values, err := cache.MGet(ctx, "small", "large", "missing")
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("value"), large, nil}, values)
+
+	ttl, err := cache.TTL(ctx, "large")
+	require.NoError(t, err)
+	require.InDelta(t, time.Hour, ttl, float64(time.Minute))
+
+	// overwrite with new generation of chunks
+	require.NoError(t, cache.Set(ctx, "large", large))
+	require.Equal(t, 5, server.len(), "chunks of previous generation should be deleted")
+
+	// overwrite with small value
+	require.NoError(t, cache.Set(ctx, "large", []byte("small now")))
+	got, err = cache.Get(ctx, "large")
+	require.NoError(t, err)
+	require.Equal(t, []byte("small now"), got)
+	require.Equal(t, 2, server.len(), "chunks of previous value should be deleted")
+
+	// evicted chunk
+	require.NoError(t, cache.Set(ctx, "evicted", large))
+	server.evict("evicted/chunk/")
+	_, err = cache.Get(ctx, "evicted")
+	require.ErrorIs(t, err, ErrNotExists, "entry without its chunks should not exist")
+	require.True(t, cache.Has(ctx, "evicted"), "has should read only the entry item")
+
+	require.NoError(t, cache.Set(ctx, "deleted", large))
+	before := server.len()
+	require.NoError(t, cache.Delete(ctx, "deleted"))
+	require.Equal(t, before-4, server.len(), "chunks should be deleted with entry")
+}
+
+func TestMemcacheKeys(t *testing.T) {
+	ctx := context.Background()
+	client, _ := newTestMemcache(t)
+	cache := NewMemcache(client)
+
+	type args struct {
+		key string
+	}
+	tests := [...]struct {
+		name string
+		args args
+	}{
+		{"plain", args{"pocket-pick:v1:0123456789abcdef:favorites"}},
+		{"space", args{"key with space"}},
+		{"long", args{strings.Repeat("k", 300)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, cache.Set(ctx, tt.args.key, []byte(tt.name)))
+			got, err := cache.Get(ctx, tt.args.key)
+			require.NoError(t, err)
+			require.Equal(t, []byte(tt.name), got)
+		})
+	}
+
+	_, err := cache.Keys(ctx, "")
+	require.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestMemcacheExpiration(t *testing.T) {
+	now := time.Now()
+
+	require.Equal(t, int32(0), memcacheExpiration(time.Time{}))
+	require.Equal(t, int32(1), memcacheExpiration(now.Add(time.Millisecond)))
+	require.Equal(t, int32(60), memcacheExpiration(now.Add(time.Minute)))
+	require.Equal(t, int32(now.Add(60*24*time.Hour).Unix()+1), memcacheExpiration(now.Add(60*24*time.Hour)), "long expiration should be unix time")
+}
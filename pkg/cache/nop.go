@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// NewNop return cache which stores nothing, values are always loaded
+func NewNop() Interface {
+	return &nopCacheImpl{}
+}
+
+type nopCacheImpl struct {
+	loads loadGroup
+}
+
+var _ Interface = (*nopCacheImpl)(nil)
+
+func (n *nopCacheImpl) Set(ctx context.Context, key string, value []byte, opts ...setOption) error {
+	return nil
+}
+
+func (n *nopCacheImpl) Get(ctx context.Context, key string) ([]byte, error) { return nil, ErrNotExists }
+
+func (n *nopCacheImpl) Has(ctx context.Context, key string) bool { return false }
+
+func (n *nopCacheImpl) Delete(ctx context.Context, keys ...string) error { return nil }
+
+func (n *nopCacheImpl) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return 0, ErrNotExists
+}
+
+func (n *nopCacheImpl) Keys(ctx context.Context, prefix string) ([]string, error) { return nil, nil }
+
+func (n *nopCacheImpl) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	return make([][]byte, len(keys)), nil
+}
+
+func (n *nopCacheImpl) MSet(ctx context.Context, values map[string][]byte, opts ...setOption) error {
+	return nil
+}
+
+func (n *nopCacheImpl) GetOrLoad(ctx context.Context, key string, loader LoaderFunc, opts ...setOption) ([]byte, error) {
+	return n.loads.getOrLoad(ctx, n, key, loader, opts)
+}
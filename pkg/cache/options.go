@@ -10,8 +10,15 @@ func WithExpire(expire time.Duration) setOption {
 	return newFuncSetOption(func(o *setOptions) { o.expire = expire })
 }
 
+// WithSoftExpire value older than soft expire is served as stale by GetOrLoad and refreshed in background
+// until it expires by WithExpire
+func WithSoftExpire(expire time.Duration) setOption {
+	return newFuncSetOption(func(o *setOptions) { o.softExpire = expire })
+}
+
 type setOptions struct {
-	expire time.Duration
+	expire     time.Duration
+	softExpire time.Duration
 }
 
 type setOption interface {
@@ -2,22 +2,180 @@ package cache
 
 import (
 	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/redis/go-redis/v9"
 )
 
+// redisSentinelPort default port of sentinel
+const redisSentinelPort = "26379"
+
+// NewRedisClient return client of redisURL, connection is not checked
+//
+//	redis://[user:password@]host:port/db, rediss:// with tls or unix:///path: standalone
+//	redis+sentinel://[user:password@]host:port/db?master=name&addr=host2:port: master found by sentinels
+//	redis+cluster://[user:password@]host:port?addr=host2:port: cluster
+func NewRedisClient(redisURL string) (redis.UniversalClient, error) {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme, mode, _ := strings.Cut(u.Scheme, "+")
+	u.Scheme = scheme
+
+	switch mode {
+	case "":
+		opts, err := redis.ParseURL(u.String())
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewClient(opts), nil
+
+	case "cluster":
+		opts, err := redis.ParseClusterURL(u.String())
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewClusterClient(opts), nil
+
+	case "sentinel":
+		return newRedisFailoverClient(u)
+
+	default:
+		return nil, errors.Errorf("invalid redis url scheme: %s", u.Scheme+"+"+mode)
+	}
+}
+
+// newRedisFailoverClient return client of master monitored by sentinels
+// query parameters except master, addr and sentinel_password are options of the master as redis://
+func newRedisFailoverClient(u *url.URL) (redis.UniversalClient, error) {
+	q := u.Query()
+	master := q.Get("master")
+	if master == "" {
+		return nil, errors.New("master name required for sentinel")
+	}
+
+	addrs := append([]string{u.Host}, q["addr"]...)
+	for i, addr := range addrs {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addrs[i] = net.JoinHostPort(addr, redisSentinelPort)
+		}
+	}
+	sentinelPassword := q.Get("sentinel_password")
+
+	for _, name := range []string{"master", "addr", "sentinel_password"} {
+		q.Del(name)
+	}
+	u.RawQuery = q.Encode()
+
+	opts, err := redis.ParseURL(u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:       master,
+		SentinelAddrs:    addrs,
+		SentinelPassword: sentinelPassword,
+		ClientName:       opts.ClientName,
+		Protocol:         opts.Protocol,
+		Username:         opts.Username,
+		Password:         opts.Password,
+		DB:               opts.DB,
+		MaxRetries:       opts.MaxRetries,
+		MinRetryBackoff:  opts.MinRetryBackoff,
+		MaxRetryBackoff:  opts.MaxRetryBackoff,
+		DialTimeout:      opts.DialTimeout,
+		ReadTimeout:      opts.ReadTimeout,
+		WriteTimeout:     opts.WriteTimeout,
+		PoolFIFO:         opts.PoolFIFO,
+		PoolSize:         opts.PoolSize,
+		PoolTimeout:      opts.PoolTimeout,
+		MinIdleConns:     opts.MinIdleConns,
+		MaxIdleConns:     opts.MaxIdleConns,
+		ConnMaxIdleTime:  opts.ConnMaxIdleTime,
+		ConnMaxLifetime:  opts.ConnMaxLifetime,
+		TLSConfig:        opts.TLSConfig,
+	}), nil
+}
+
+// redisSlots number of hash slots of redis cluster
+const redisSlots = 16384
+
+// redisSlot return cluster hash slot of key, only hash tag in braces is hashed if the key has it
+func redisSlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	// crc16 xmodem
+	var crc uint16
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return int(crc) % redisSlots
+}
+
+// redisSlotGroups group indexes of keys by hash slot, as multi-key commands of cluster take keys of one slot
+func redisSlotGroups(keys []string) [][]int {
+	var groups [][]int
+	slots := make(map[int]int) // slot to index of group
+	for i, key := range keys {
+		slot := redisSlot(key)
+		g, ok := slots[slot]
+		if !ok {
+			g = len(groups)
+			slots[slot] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
+
+func pickKeys(keys []string, indexes []int) []string {
+	picked := make([]string, len(indexes))
+	for i, index := range indexes {
+		picked[i] = keys[index]
+	}
+	return picked
+}
+
 type redisCacheImpl struct {
-	client *redis.Client
+	client redis.UniversalClient
+	loads  loadGroup
 }
 
 var _ Interface = (*redisCacheImpl)(nil)
 
-func NewRedis(r *redis.Client) Interface {
+// NewRedis return cache stored in redis; r is client of standalone, sentinel or cluster
+// with cluster, multi-key commands are split by hash slot and sent in a pipeline
+func NewRedis(r redis.UniversalClient) Interface {
 	return &redisCacheImpl{
 		client: r,
 	}
 }
 
+func (r *redisCacheImpl) cluster() bool {
+	_, ok := r.client.(*redis.ClusterClient)
+	return ok
+}
+
 func (r *redisCacheImpl) Set(ctx context.Context, key string, value []byte, opts ...setOption) error {
 	option := applySetOptions(opts)
 
@@ -38,3 +196,132 @@ func (r *redisCacheImpl) Has(ctx context.Context, key string) bool {
 	exists, _ := r.client.Exists(ctx, key).Result()
 	return exists != 0
 }
+
+func (r *redisCacheImpl) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if !r.cluster() {
+		return r.client.Del(ctx, keys...).Err()
+	}
+
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, group := range redisSlotGroups(keys) {
+			pipe.Del(ctx, pickKeys(keys, group)...)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *redisCacheImpl) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	switch ttl {
+	case -2:
+		return 0, ErrNotExists
+	case -1:
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// redisGlobEscaper escape glob pattern characters of SCAN MATCH
+var redisGlobEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+func (r *redisCacheImpl) Keys(ctx context.Context, prefix string) ([]string, error) {
+	match := redisGlobEscaper.Replace(prefix) + "*"
+
+	cluster, ok := r.client.(*redis.ClusterClient)
+	if !ok {
+		return redisScan(ctx, r.client, match)
+	}
+
+	// keys are spread over masters of cluster
+	var mu sync.Mutex
+	var keys []string
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		found, err := redisScan(ctx, client, match)
+
+		mu.Lock()
+		keys = append(keys, found...)
+		mu.Unlock()
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func redisScan(ctx context.Context, client redis.Cmdable, match string) ([]string, error) {
+	var keys []string
+
+	it := client.Scan(ctx, 0, match, 100).Iterator()
+	for it.Next(ctx) {
+		keys = append(keys, it.Val())
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *redisCacheImpl) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	var groups [][]int
+	if r.cluster() {
+		groups = redisSlotGroups(keys)
+	} else {
+		all := make([]int, len(keys))
+		for i := range all {
+			all[i] = i
+		}
+		groups = [][]int{all}
+	}
+
+	cmds := make([]*redis.SliceCmd, len(groups))
+	if _, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, group := range groups {
+			cmds[i] = pipe.MGet(ctx, pickKeys(keys, group)...)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	values := make([][]byte, len(keys))
+	for i, group := range groups {
+		for j, result := range cmds[i].Val() {
+			if v, ok := result.(string); ok {
+				values[group[j]] = []byte(v)
+			}
+		}
+	}
+	return values, nil
+}
+
+func (r *redisCacheImpl) MSet(ctx context.Context, values map[string][]byte, opts ...setOption) error {
+	option := applySetOptions(opts)
+
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, value := range values {
+			pipe.Set(ctx, key, value, option.expire)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *redisCacheImpl) GetOrLoad(ctx context.Context, key string, loader LoaderFunc, opts ...setOption) ([]byte, error) {
+	return r.loads.getOrLoad(ctx, r, key, loader, opts)
+}
@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRedisCluster return cluster client of new redis server, which owns all slots
+// miniredis takes keys of any slots, so multi-key commands over slots are rejected as redis cluster does
+func newTestRedisCluster(t *testing.T) *redis.ClusterClient {
+	s := miniredis.RunT(t)
+	r := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{s.Addr()}})
+	r.AddHook(crossSlotHook{})
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+// newTestRedisSentinel return redis server and sentinel which monitors it as master
+func newTestRedisSentinel(t *testing.T, master string) (*miniredis.Miniredis, *miniredis.Miniredis) {
+	s := miniredis.RunT(t)
+	sentinel := miniredis.RunT(t)
+
+	require.NoError(t, sentinel.Server().Register("SENTINEL", func(c *server.Peer, cmd string, args []string) {
+		switch strings.ToLower(args[0]) {
+		case "get-master-addr-by-name":
+			if args[1] != master {
+				c.WriteNull()
+				return
+			}
+			c.WriteStrings([]string{s.Host(), s.Port()})
+		case "sentinels", "replicas", "slaves":
+			c.WriteLen(0)
+		default:
+			c.WriteError("ERR unknown sentinel subcommand: " + args[0])
+		}
+	}))
+
+	return s, sentinel
+}
+
+// crossSlotHook fail multi-key commands whose keys are in different slots
+type crossSlotHook struct{}
+
+func (crossSlotHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (crossSlotHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := checkSlot(cmd); err != nil {
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (crossSlotHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if err := checkSlot(cmd); err != nil {
+				return err
+			}
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func checkSlot(cmd redis.Cmder) error {
+	args := cmd.Args()
+
+	var keys []any
+	switch strings.ToLower(cmd.Name()) {
+	case "mget", "del", "exists", "unlink":
+		keys = args[1:]
+	case "eval", "evalsha":
+		n, _ := strconv.Atoi(fmt.Sprint(args[2]))
+		keys = args[3 : 3+n]
+	}
+
+	for _, key := range keys {
+		if redisSlot(fmt.Sprint(key)) != redisSlot(fmt.Sprint(keys[0])) {
+			err := fmt.Errorf("CROSSSLOT Keys in request don't hash to the same slot: %v", keys)
+			cmd.SetErr(err)
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRedisSlot(t *testing.T) {
+	type args struct {
+		key string
+	}
+	tests := [...]struct {
+		name string
+		args args
+		want int
+	}{
+		{"plain", args{"123456789"}, 12739},
+		{"foo", args{"foo"}, 12182},
+		{"hash tag", args{"{user1000}.following"}, redisSlot("user1000")},
+		{"first hash tag", args{"foo{bar}{zap}"}, redisSlot("bar")},
+		{"empty hash tag", args{"foo{}{bar}"}, redisSlot("foo{}{bar}")},
+		{"nested hash tag", args{"lock:{app:v2:{hash}:lock/trash}"}, redisSlot("app:v2:{hash")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, redisSlot(tt.args.key))
+		})
+	}
+
+	require.Equal(t, [][]int{{0, 2}, {1}}, redisSlotGroups([]string{"{a}1", "{b}1", "{a}2"}))
+}
+
+func TestRedisCluster(t *testing.T) {
+	ctx := context.Background()
+	cache := NewRedis(newTestRedisCluster(t))
+
+	keys := []string{"key1", "key2", "{user}:favorites", "{user}:version"}
+	require.NotEqual(t, redisSlot(keys[0]), redisSlot(keys[1]))
+
+	values := map[string][]byte{}
+	for _, key := range keys {
+		values[key] = []byte("value of " + key)
+	}
+	require.NoError(t, cache.MSet(ctx, values))
+
+	got, err := cache.MGet(ctx, append(keys, "not-exists")...)
+	require.NoError(t, err)
+	for i, key := range keys {
+		require.Equal(t, values[key], got[i])
+	}
+	require.Nil(t, got[len(keys)])
+
+	found, err := cache.Keys(ctx, "")
+	require.NoError(t, err)
+	require.ElementsMatch(t, keys, found)
+
+	require.NoError(t, cache.Delete(ctx, keys...))
+	found, err = cache.Keys(ctx, "")
+	require.NoError(t, err)
+	require.Empty(t, found)
+}
+
+func TestNewRedisClient(t *testing.T) {
+	s := miniredis.RunT(t)
+	master, sentinel := newTestRedisSentinel(t, "mymaster")
+
+	type args struct {
+		url string
+	}
+	tests := [...]struct {
+		name    string
+		args    args
+		want    *miniredis.Miniredis // server which stores the value
+		wantErr bool
+	}{
+		{"standalone", args{"redis://" + s.Addr()}, s, false},
+		{"cluster", args{"redis+cluster://" + s.Addr() + "?dial_timeout=3s"}, s, false},
+		{"sentinel", args{"redis+sentinel://" + sentinel.Addr() + "/0?master=mymaster&addr=127.0.0.1:1"}, master, false},
+		{"sentinel without master", args{"redis+sentinel://" + sentinel.Addr()}, nil, true},
+		{"sentinel invalid option", args{"redis+sentinel://" + sentinel.Addr() + "?master=mymaster&unknown=1"}, nil, true},
+		{"unknown mode", args{"redis+unknown://" + s.Addr()}, nil, true},
+		{"invalid scheme", args{"http://" + s.Addr()}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			r, err := NewRedisClient(tt.args.url)
+			require.Truef(t, (err != nil) == tt.wantErr, "NewRedisClient() error = %v, wantErr %v", err, tt.wantErr)
+			if tt.wantErr {
+				return
+			}
+			defer r.Close()
+
+			require.NoError(t, r.Set(ctx, "key", tt.name, 0).Err())
+			got, err := tt.want.Get("key")
+			require.NoError(t, err)
+			require.Equal(t, tt.name, got)
+		})
+	}
+}
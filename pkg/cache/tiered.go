@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// invalidation messages are published as "<instance id> <key>"
+const tieredInvalidateChannel = "cache:invalidate"
+
+// NewTiered return cache which layers in-process bigcache(L1) in front of redis(L2)
+// reads check L1 then L2 and promote L2 hits to L1; L1 entries live at most l1TTL
+// writes go to both tiers and other instances drop their L1 copies by redis pub/sub
+func NewTiered(ctx context.Context, r redis.UniversalClient, l1TTL time.Duration) (Interface, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	t := &tieredCacheImpl{
+		id:    hex.EncodeToString(id),
+		l1:    NewBigCache(ctx).(*bigCacheImpl),
+		l2:    NewRedis(r).(*redisCacheImpl),
+		l1TTL: l1TTL,
+	}
+
+	sub := r.Subscribe(ctx, tieredInvalidateChannel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, errors.Wrap(err, "subscribe failed")
+	}
+
+	go func() {
+		defer sub.Close()
+
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				t.invalidated(msg.Payload)
+			}
+		}
+	}()
+
+	return t, nil
+}
+
+type tieredCacheImpl struct {
+	id    string // instance id to ignore own invalidation messages
+	l1    *bigCacheImpl
+	l2    *redisCacheImpl
+	l1TTL time.Duration
+	loads loadGroup
+}
+
+var _ Interface = (*tieredCacheImpl)(nil)
+
+// backendStats return stats of L1 with l1_ prefix
+func (t *tieredCacheImpl) backendStats() map[string]int64 {
+	stats := make(map[string]int64)
+	for name, value := range t.l1.backendStats() {
+		stats["l1_"+name] = value
+	}
+	return stats
+}
+
+func (t *tieredCacheImpl) Set(ctx context.Context, key string, value []byte, opts ...setOption) error {
+	return t.MSet(ctx, map[string][]byte{key: value}, opts...)
+}
+
+func (t *tieredCacheImpl) MSet(ctx context.Context, values map[string][]byte, opts ...setOption) error {
+	if err := t.l2.MSet(ctx, values, opts...); err != nil {
+		return err
+	}
+
+	if err := t.l1.MSet(ctx, values, WithExpire(t.l1Expire(applySetOptions(opts).expire))); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	return t.invalidate(ctx, keys...)
+}
+
+func (t *tieredCacheImpl) Delete(ctx context.Context, keys ...string) error {
+	if err := t.l2.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	if err := t.l1.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	return t.invalidate(ctx, keys...)
+}
+
+// invalidate broadcast changed keys to other instances
+func (t *tieredCacheImpl) invalidate(ctx context.Context, keys ...string) error {
+	_, err := t.l2.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Publish(ctx, tieredInvalidateChannel, t.id+" "+key)
+		}
+		return nil
+	})
+	return err
+}
+
+// l1Expire return expiration for L1, capped by l1TTL
+func (t *tieredCacheImpl) l1Expire(expire time.Duration) time.Duration {
+	if expire <= 0 || expire > t.l1TTL {
+		return t.l1TTL
+	}
+	return expire
+}
+
+func (t *tieredCacheImpl) Get(ctx context.Context, key string) ([]byte, error) {
+	values, err := t.MGet(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if values[0] == nil {
+		return nil, ErrNotExists
+	}
+	return values[0], nil
+}
+
+func (t *tieredCacheImpl) MGet(ctx context.Context, keys ...string) ([][]byte, error) {
+	values, err := t.l1.MGet(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	gets := map[int]*redis.StringCmd{}
+	ttls := map[int]*redis.DurationCmd{}
+	if _, err := t.l2.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			if values[i] == nil {
+				gets[i] = pipe.Get(ctx, key)
+				ttls[i] = pipe.PTTL(ctx, key)
+			}
+		}
+		return nil
+	}); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	for i, get := range gets {
+		data, err := get.Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// promote to L1 with remaining ttl of L2
+		if err := t.l1.Set(ctx, keys[i], data, WithExpire(t.l1Expire(ttls[i].Val()))); err != nil {
+			return nil, err
+		}
+		values[i] = data
+	}
+
+	return values, nil
+}
+
+func (t *tieredCacheImpl) Has(ctx context.Context, key string) bool {
+	return t.l1.Has(ctx, key) || t.l2.Has(ctx, key)
+}
+
+func (t *tieredCacheImpl) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return t.l2.TTL(ctx, key)
+}
+
+func (t *tieredCacheImpl) Keys(ctx context.Context, prefix string) ([]string, error) {
+	return t.l2.Keys(ctx, prefix)
+}
+
+func (t *tieredCacheImpl) GetOrLoad(ctx context.Context, key string, loader LoaderFunc, opts ...setOption) ([]byte, error) {
+	return t.loads.getOrLoad(ctx, t, key, loader, opts)
+}
+
+// invalidated drop L1 copy of the key changed by other instance
+func (t *tieredCacheImpl) invalidated(payload string) {
+	id, key, ok := strings.Cut(payload, " ")
+	if !ok || id == t.id {
+		return
+	}
+
+	t.l1.cache.Delete(key)
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRedis return client of new redis server, not shared with other tests
+func newTestRedis(t *testing.T) *redis.Client {
+	s := miniredis.RunT(t)
+	return redis.NewClient(&redis.Options{Addr: s.Addr()})
+}
+
+func newTestTiered(t *testing.T, r redis.UniversalClient) Interface {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cache, err := NewTiered(ctx, r, time.Minute)
+	require.NoError(t, err)
+	return cache
+}
+
+func TestTieredInvalidate(t *testing.T) {
+	s := miniredis.RunT(t)
+	r := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer s.Close()
+
+	ctx := context.Background()
+	key := "sample-key"
+
+	// two instances share the redis
+	c1 := newTestTiered(t, r)
+	c2 := newTestTiered(t, r)
+
+	require.NoError(t, c1.Set(ctx, key, []byte("value-1")))
+	got, err := c2.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("value-1"), got)
+
+	// promoted to L1
+	s.Del(key)
+	got, err = c2.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("value-1"), got, "should be served from L1")
+
+	// L1 copy of other instance is dropped
+	require.NoError(t, c1.Set(ctx, key, []byte("value-2")))
+	require.Eventually(t, func() bool {
+		got, err := c2.Get(ctx, key)
+		return err == nil && string(got) == "value-2"
+	}, time.Second, 10*time.Millisecond)
+
+	got, err = c1.Get(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, []byte("value-2"), got)
+}
+
+func TestTieredL1Expire(t *testing.T) {
+	s := miniredis.RunT(t)
+	r := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer s.Close()
+
+	ctx := context.Background()
+	c := newTestTiered(t, r)
+	tiered := c.(*tieredCacheImpl)
+
+	require.Equal(t, time.Minute, tiered.l1Expire(0))
+	require.Equal(t, time.Minute, tiered.l1Expire(time.Hour))
+	require.Equal(t, time.Second, tiered.l1Expire(time.Second))
+
+	require.NoError(t, c.Set(ctx, "sample-key", []byte("value"), WithExpire(time.Hour)))
+	require.Equal(t, time.Hour, s.TTL("sample-key"), "L2 keeps its own ttl")
+}
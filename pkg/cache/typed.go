@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Serializer marshal values of Typed cache
+type Serializer interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// serializers
+var (
+	JSON    Serializer = jsonSerializer{}
+	Gob     Serializer = gobSerializer{}
+	Msgpack Serializer = msgpackSerializer{}
+)
+
+var serializers = map[string]Serializer{
+	"json":    JSON,
+	"gob":     Gob,
+	"msgpack": Msgpack,
+}
+
+// ParseSerializer return serializer by name
+func ParseSerializer(name string) (Serializer, error) {
+	if serializer, ok := serializers[name]; ok {
+		return serializer, nil
+	}
+	return nil, errors.Errorf("unknown serializer: %s", name)
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonSerializer) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type gobSerializer struct{}
+
+func (gobSerializer) Marshal(v any) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobSerializer) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// msgpackSerializer use json struct tags, so types tagged for json are serialized with the same field names
+type msgpackSerializer struct{}
+
+func (msgpackSerializer) Marshal(v any) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := msgpack.NewEncoder(buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackSerializer) Unmarshal(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// Typed cache which stores values of type T with serializer
+type Typed[T any] struct {
+	cache      Interface
+	serializer Serializer
+}
+
+// NewTyped return typed cache over c
+func NewTyped[T any](c Interface, serializer Serializer) *Typed[T] {
+	return &Typed[T]{
+		cache:      c,
+		serializer: serializer,
+	}
+}
+
+func (t *Typed[T]) Set(ctx context.Context, key string, value T, opts ...setOption) error {
+	data, err := t.serializer.Marshal(value)
+	if err != nil {
+		return errors.Wrap(err, "encode failed")
+	}
+
+	return t.cache.Set(ctx, key, data, opts...)
+}
+
+// Get return ErrNotExists if key not exists
+// value stored by GetOrLoad with soft expire is returned without its refresh time
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, error) {
+	data, err := t.cache.Get(ctx, key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	value, _ := splitRefreshAt(data)
+	return t.decode(value)
+}
+
+func (t *Typed[T]) decode(data []byte) (T, error) {
+	var value T
+	if err := t.serializer.Unmarshal(data, &value); err != nil {
+		var zero T
+		return zero, errors.Wrap(err, "decode failed")
+	}
+
+	return value, nil
+}
+
+// Replace set value of existing key, keeping its expiration and refresh time of soft expire
+// return ErrNotExists if key not exists
+func (t *Typed[T]) Replace(ctx context.Context, key string, value T) error {
+	current, err := t.cache.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	ttl, err := t.cache.TTL(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	data, err := t.serializer.Marshal(value)
+	if err != nil {
+		return errors.Wrap(err, "encode failed")
+	}
+
+	if _, refreshAt := splitRefreshAt(current); !refreshAt.IsZero() {
+		data = withRefreshAt(data, refreshAt)
+	}
+
+	return t.cache.Set(ctx, key, data, WithExpire(ttl))
+}
+
+func (t *Typed[T]) Delete(ctx context.Context, keys ...string) error {
+	return t.cache.Delete(ctx, keys...)
+}
+
+// Has return true if the key exists; see Interface.Has
+func (t *Typed[T]) Has(ctx context.Context, key string) bool {
+	return t.cache.Has(ctx, key)
+}
+
+// TTL return remaining time to live of the key; see Interface.TTL
+func (t *Typed[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return t.cache.TTL(ctx, key)
+}
+
+// Keys return keys which start with prefix; see Interface.Keys
+func (t *Typed[T]) Keys(ctx context.Context, prefix string) ([]string, error) {
+	return t.cache.Keys(ctx, prefix)
+}
+
+// GetOrLoad return cached value, or load value with loader; see Interface.GetOrLoad
+// value which can not be decoded, e.g. written with other serializer, is loaded again
+func (t *Typed[T]) GetOrLoad(ctx context.Context, key string, loader func(ctx context.Context) (T, error), opts ...setOption) (T, error) {
+	load := func(ctx context.Context) ([]byte, error) {
+		value, err := loader(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := t.serializer.Marshal(value)
+		if err != nil {
+			return nil, errors.Wrap(err, "encode failed")
+		}
+		return data, nil
+	}
+
+	data, err := t.cache.GetOrLoad(ctx, key, load, opts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	value, err := t.decode(data)
+	if err == nil {
+		return value, nil
+	}
+
+	if err := t.cache.Delete(ctx, key); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	data, err = t.cache.GetOrLoad(ctx, key, load, opts...)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return t.decode(data)
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTyped(t *testing.T) {
+	articles := map[string]*sampleArticle{}
+	require.NoError(t, json.Unmarshal(sampleArticles(10), &articles))
+
+	type args struct {
+		serializer Serializer
+	}
+	tests := [...]struct {
+		name string
+		args args
+	}{
+		{"json", args{JSON}},
+		{"gob", args{Gob}},
+		{"msgpack", args{Msgpack}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			typed := NewTyped[map[string]*sampleArticle](NewBigCache(ctx), tt.args.serializer)
+
+			_, err := typed.Get(ctx, "articles")
+			require.ErrorIs(t, err, ErrNotExists)
+
+			require.NoError(t, typed.Set(ctx, "articles", articles))
+			got, err := typed.Get(ctx, "articles")
+			require.NoError(t, err)
+			require.Equal(t, articles, got)
+
+			var loads atomic.Int32
+			loader := func(ctx context.Context) (map[string]*sampleArticle, error) {
+				loads.Add(1)
+				return articles, nil
+			}
+
+			got, err = typed.GetOrLoad(ctx, "loaded", loader, WithExpire(time.Minute))
+			require.NoError(t, err)
+			require.Equal(t, articles, got)
+
+			got, err = typed.GetOrLoad(ctx, "loaded", loader, WithExpire(time.Minute))
+			require.NoError(t, err)
+			require.Equal(t, articles, got)
+			require.Equal(t, int32(1), loads.Load(), "should load from cache")
+		})
+	}
+}
+
+func TestTypedSerializerChanged(t *testing.T) {
+	ctx := context.Background()
+	c := NewBigCache(ctx)
+
+	require.NoError(t, NewTyped[[]string](c, JSON).Set(ctx, "key", []string{"old"}))
+
+	typed := NewTyped[[]string](c, Gob)
+	_, err := typed.Get(ctx, "key")
+	require.Error(t, err)
+
+	got, err := typed.GetOrLoad(ctx, "key", func(ctx context.Context) ([]string, error) { return []string{"new"}, nil })
+	require.NoError(t, err)
+	require.Equal(t, []string{"new"}, got, "value of other serializer should be loaded again")
+}
+
+func TestTypedSoftExpire(t *testing.T) {
+	ctx := context.Background()
+	typed := NewTyped[[]string](NewBigCache(ctx), Msgpack)
+
+	var loads atomic.Int32
+	loader := func(ctx context.Context) ([]string, error) {
+		loads.Add(1)
+		return []string{"loaded"}, nil
+	}
+
+	got, err := typed.GetOrLoad(ctx, "key", loader, WithExpire(time.Hour), WithSoftExpire(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []string{"loaded"}, got)
+
+	got, err = typed.Get(ctx, "key")
+	require.NoError(t, err)
+	require.Equal(t, []string{"loaded"}, got, "refresh time should not be returned")
+
+	// replace keeps refresh time, so value is not refreshed
+	require.NoError(t, typed.Replace(ctx, "key", []string{"replaced"}))
+	got, err = typed.GetOrLoad(ctx, "key", loader, WithExpire(time.Hour), WithSoftExpire(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []string{"replaced"}, got)
+	require.Equal(t, int32(1), loads.Load())
+
+	require.ErrorIs(t, typed.Replace(ctx, "not-exists", nil), ErrNotExists)
+}
+
+func BenchmarkSerializer(b *testing.B) {
+	articles := map[string]*sampleArticle{}
+	require.NoError(b, json.Unmarshal(sampleArticles(1000), &articles))
+
+	type args struct {
+		serializer Serializer
+	}
+	tests := [...]struct {
+		name string
+		args args
+	}{
+		{"json", args{JSON}},
+		{"gob", args{Gob}},
+		{"msgpack", args{Msgpack}},
+	}
+	for _, tt := range tests {
+		data, err := tt.args.serializer.Marshal(articles)
+		require.NoError(b, err)
+
+		b.Run(tt.name+"-marshal", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := tt.args.serializer.Marshal(articles)
+				require.NoError(b, err)
+			}
+			b.ReportMetric(float64(len(data)), "bytes")
+		})
+
+		b.Run(tt.name+"-unmarshal", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				var got map[string]*sampleArticle
+				require.NoError(b, tt.args.serializer.Unmarshal(data, &got))
+			}
+		})
+	}
+}
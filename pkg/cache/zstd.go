@@ -1,44 +1,67 @@
 package cache
 
 import (
-	"bytes"
-	"io"
 	"runtime"
+	"sync"
 
+	"github.com/klauspost/compress/dict"
 	"github.com/klauspost/compress/zstd"
 )
 
-// zstdCompress compress with klauspost zstd
-func zstdCompress(src []byte) ([]byte, error) {
-	out := bytes.NewBuffer(nil)
-	enc, err := zstd.NewWriter(out, zstd.WithEncoderConcurrency(runtime.NumCPU()))
-	if err != nil {
-		return nil, err
+// zstdCodec compress with klauspost zstd
+// EncodeAll and DecodeAll are safe for concurrent use and reuse internal encoders/decoders up to GOMAXPROCS
+type zstdCodec struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+// newZstdCodec return zstd codec with optional dictionary
+// decoder also reads values compressed without dictionary
+func newZstdCodec(dictionary []byte) (*zstdCodec, error) {
+	eopts := []zstd.EOption{zstd.WithEncoderConcurrency(runtime.GOMAXPROCS(0))}
+	dopts := []zstd.DOption{zstd.WithDecoderConcurrency(runtime.GOMAXPROCS(0))}
+	if dictionary != nil {
+		eopts = append(eopts, zstd.WithEncoderDict(dictionary))
+		dopts = append(dopts, zstd.WithDecoderDicts(dictionary))
 	}
 
-	if _, err = io.Copy(enc, bytes.NewReader(src)); err != nil {
+	enc, err := zstd.NewWriter(nil, eopts...)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := enc.Close(); err != nil {
+	dec, err := zstd.NewReader(nil, dopts...)
+	if err != nil {
 		return nil, err
 	}
 
-	return out.Bytes(), nil
+	return &zstdCodec{enc: enc, dec: dec}, nil
 }
 
-// zstdDecompress decompress with klauspost zstd
-func zstdDecompress(src []byte) ([]byte, error) {
-	dec, err := zstd.NewReader(bytes.NewReader(src), zstd.WithDecoderConcurrency(runtime.NumCPU()))
+func (z *zstdCodec) compress(src []byte) []byte { return z.enc.EncodeAll(src, nil) }
+
+func (z *zstdCodec) decompress(src []byte) ([]byte, error) { return z.dec.DecodeAll(src, nil) }
+
+// defaultZstd shared zstd codec without dictionary
+var defaultZstd = sync.OnceValue(func() *zstdCodec {
+	z, err := newZstdCodec(nil)
 	if err != nil {
-		return nil, err
+		panic(err)
 	}
-	defer dec.Close()
+	return z
+})
 
-	out := bytes.NewBuffer(nil)
-	if _, err = io.Copy(out, dec); err != nil {
-		return nil, err
-	}
+// zstdCompress compress with klauspost zstd
+func zstdCompress(src []byte) ([]byte, error) { return defaultZstd().compress(src), nil }
+
+// zstdDecompress decompress with klauspost zstd
+func zstdDecompress(src []byte) ([]byte, error) { return defaultZstd().decompress(src) }
 
-	return out.Bytes(), nil
+// TrainZstdDictionary return zstd dictionary trained from samples of representative values
+// such as article list json; use it with WithZstdDictionary
+func TrainZstdDictionary(samples [][]byte) ([]byte, error) {
+	return dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize: 64 << 10,
+		HashBytes:   6,
+	})
 }
@@ -1,8 +1,13 @@
 package cache
 
 import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/DataDog/zstd"
@@ -16,24 +21,39 @@ func zstdDatadogCompress(src []byte) ([]byte, error) { return zstd.Compress(nil,
 func zstdDatadogDecompress(src []byte) ([]byte, error) { return zstd.Decompress(nil, src) }
 
 // see https://github.com/klauspost/compress/tree/master/zstd#performance
+// articles are compressed also with dictionary trained by TrainZstdDictionary
 func BenchmarkZstdCompress(b *testing.B) {
+	withDict := newTestZstdDict(b)
+
 	type args struct {
-		in string
+		in       string
+		articles int // sample articles if in is empty
 	}
 	tests := [...]struct {
 		name string
 		args args
 	}{
-		{`silesia.tar`, args{"silesia.tar"}},
-		{`github-june-2days-2019.json`, args{"github-june-2days-2019.json"}},
-		{`gob-stream`, args{"gob-stream"}},
-		{`textdata.html`, args{"textdata.html"}},
+		{`silesia.tar`, args{"silesia.tar", 0}},
+		{`github-june-2days-2019.json`, args{"github-june-2days-2019.json", 0}},
+		{`gob-stream`, args{"gob-stream", 0}},
+		{`textdata.html`, args{"textdata.html", 0}},
+		{`articles-1`, args{"", 1}},
+		{`articles-20`, args{"", 20}},
+		{`articles-1000`, args{"", 1000}},
 	}
 
 	for _, tt := range tests {
 		b.StopTimer()
-		data, err := os.ReadFile(path.Join("fixtures", tt.args.in))
-		require.NoError(b, err)
+		data := sampleArticles(tt.args.articles)
+		if tt.args.in != "" {
+			var err error
+			data, err = os.ReadFile(path.Join("fixtures", tt.args.in))
+			if os.IsNotExist(err) {
+				b.Logf("fixture not downloaded, skipped: %s", tt.args.in)
+				continue
+			}
+			require.NoError(b, err)
+		}
 		b.StartTimer()
 
 		b.Run(tt.name+"-zstd", func(b *testing.B) {
@@ -45,29 +65,50 @@ func BenchmarkZstdCompress(b *testing.B) {
 		b.Run(tt.name+"-zskp", func(b *testing.B) {
 			out, err := zstdCompress(data)
 			require.NoError(b, err)
-			_ = out
+			b.ReportMetric(float64(len(out))/float64(len(data)), "ratio")
 		})
+
+		if tt.args.articles > 0 {
+			b.Run(tt.name+"-zskp-dict", func(b *testing.B) {
+				out := withDict.compress(data)
+				b.ReportMetric(float64(len(out))/float64(len(data)), "ratio")
+			})
+		}
 	}
 }
 
 func BenchmarkZstdDecompress(b *testing.B) {
+	withDict := newTestZstdDict(b)
+
 	type args struct {
-		in string
+		in       string
+		articles int // sample articles if in is empty
 	}
 	tests := [...]struct {
 		name string
 		args args
 	}{
-		{`silesia.tar`, args{"silesia.tar.zst"}},
-		{`github-june-2days-2019.json`, args{"github-june-2days-2019.json.zst"}},
-		{`gob-stream`, args{"gob-stream.zst"}},
-		{`textdata.html`, args{"textdata.html.zst"}},
+		{`silesia.tar`, args{"silesia.tar.zst", 0}},
+		{`github-june-2days-2019.json`, args{"github-june-2days-2019.json.zst", 0}},
+		{`gob-stream`, args{"gob-stream.zst", 0}},
+		{`textdata.html`, args{"textdata.html.zst", 0}},
+		{`articles-1`, args{"", 1}},
+		{`articles-20`, args{"", 20}},
+		{`articles-1000`, args{"", 1000}},
 	}
 
 	for _, tt := range tests {
 		b.StopTimer()
-		data, err := os.ReadFile(path.Join("fixtures", tt.args.in))
-		require.NoError(b, err)
+		data := defaultZstd().compress(sampleArticles(tt.args.articles))
+		if tt.args.in != "" {
+			var err error
+			data, err = os.ReadFile(path.Join("fixtures", tt.args.in))
+			if os.IsNotExist(err) {
+				b.Logf("fixture not downloaded, skipped: %s", tt.args.in)
+				continue
+			}
+			require.NoError(b, err)
+		}
 		b.StartTimer()
 
 		b.Run(tt.name+"-zstd", func(b *testing.B) {
@@ -81,5 +122,113 @@ func BenchmarkZstdDecompress(b *testing.B) {
 			require.NoError(b, err)
 			_ = out
 		})
+
+		if tt.args.articles > 0 {
+			compressed := withDict.compress(sampleArticles(tt.args.articles))
+			b.Run(tt.name+"-zskp-dict", func(b *testing.B) {
+				out, err := withDict.decompress(compressed)
+				require.NoError(b, err)
+				_ = out
+			})
+		}
+	}
+}
+
+// newTestZstdDict return zstd codec with dictionary trained with sample articles
+func newTestZstdDict(t testing.TB) *zstdCodec {
+	var samples [][]byte
+	for i := 1; i <= 50; i++ {
+		samples = append(samples, sampleArticles(i))
+	}
+
+	dictionary, err := TrainZstdDictionary(samples)
+	require.NoError(t, err)
+
+	z, err := newZstdCodec(dictionary)
+	require.NoError(t, err)
+	return z
+}
+
+type sampleTag struct {
+	ItemID string `json:"item_id"`
+	Tag    string `json:"tag"`
+}
+
+// sampleArticle article similar to getpocket article
+type sampleArticle struct {
+	ItemID        string               `json:"item_id"`
+	ResolvedID    string               `json:"resolved_id"`
+	GivenURL      string               `json:"given_url"`
+	GivenTitle    string               `json:"given_title"`
+	Favorite      string               `json:"favorite"`
+	Status        string               `json:"status"`
+	TimeAdded     string               `json:"time_added"`
+	TimeUpdated   string               `json:"time_updated"`
+	ResolvedTitle string               `json:"resolved_title"`
+	ResolvedURL   string               `json:"resolved_url"`
+	Excerpt       string               `json:"excerpt"`
+	IsArticle     string               `json:"is_article"`
+	HasImage      string               `json:"has_image"`
+	WordCount     string               `json:"word_count"`
+	Lang          string               `json:"lang"`
+	Tags          map[string]sampleTag `json:"tags,omitempty"`
+}
+
+// sampleArticles return json of n articles similar to getpocket article list
+func sampleArticles(n int) []byte {
+	rnd := rand.New(rand.NewSource(int64(n)))
+	domains := []string{"github.com", "medium.com", "news.ycombinator.com", "go.dev", "blog.cloudflare.com"}
+	words := strings.Fields("go cache redis pocket article reading performance kubernetes database design distributed systems compression")
+
+	articles := map[string]*sampleArticle{}
+	for i := 0; i < n; i++ {
+		itemID := strconv.Itoa(1000000000 + rnd.Intn(1000000000))
+		title := make([]string, 3+rnd.Intn(6))
+		for j := range title {
+			title[j] = words[rnd.Intn(len(words))]
+		}
+		url := fmt.Sprintf("https://%s/%s-%d", domains[rnd.Intn(len(domains))], strings.Join(title, "-"), i)
+		tagName := words[rnd.Intn(len(words))]
+
+		articles[itemID] = &sampleArticle{
+			ItemID:        itemID,
+			ResolvedID:    itemID,
+			GivenURL:      url,
+			Favorite:      "1",
+			Status:        "0",
+			TimeAdded:     strconv.Itoa(1600000000 + rnd.Intn(100000000)),
+			TimeUpdated:   strconv.Itoa(1600000000 + rnd.Intn(100000000)),
+			ResolvedTitle: strings.Join(title, " "),
+			ResolvedURL:   url,
+			Excerpt:       strings.Repeat(strings.Join(title, " ")+". ", 4),
+			IsArticle:     "1",
+			HasImage:      "1",
+			WordCount:     strconv.Itoa(rnd.Intn(5000)),
+			Lang:          "en",
+			Tags:          map[string]sampleTag{tagName: {ItemID: itemID, Tag: tagName}},
+		}
 	}
+
+	data, _ := json.Marshal(articles)
+	return data
+}
+
+func TestZstdDictionary(t *testing.T) {
+	z := newTestZstdDict(t)
+
+	value := sampleArticles(10)
+	compressed := z.compress(value)
+	require.Less(t, len(compressed), len(defaultZstd().compress(value)), "dictionary should improve compression")
+
+	got, err := z.decompress(compressed)
+	require.NoError(t, err)
+	require.Equal(t, value, got)
+
+	// values compressed without dictionary are readable
+	got, err = z.decompress(defaultZstd().compress(value))
+	require.NoError(t, err)
+	require.Equal(t, value, got)
+
+	_, err = defaultZstd().decompress(compressed)
+	require.Error(t, err, "dictionary required")
 }
@@ -0,0 +1,159 @@
+package pocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/whitekid/getpocket"
+
+	"pocket-pick/config"
+)
+
+// pocketAPIURL base url of getpocket api
+// getpocket client does not return results of each action and since of get, so those apis are called directly
+var pocketAPIURL = "https://getpocket.com/v3"
+
+// pocketAction action of send api
+type pocketAction struct {
+	Action string `json:"action"`
+	ItemID string `json:"item_id"`
+	Tags   string `json:"tags,omitempty"` // comma separated tags
+}
+
+type pocketSendRequest struct {
+	ConsumerKey string          `json:"consumer_key"`
+	AccessToken string          `json:"access_token"`
+	Actions     []*pocketAction `json:"actions"`
+}
+
+// pocketSendResponse response of send api, results and errors are in the order of actions
+// result is false if the action failed, or object for some actions
+type pocketSendResponse struct {
+	Status        int               `json:"status"`
+	ActionResults []json.RawMessage `json:"action_results"`
+	ActionErrors  []*pocketError    `json:"action_errors"`
+}
+
+type pocketError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+	Code    int    `json:"code"`
+}
+
+// succeeded return true if i-th action succeeded
+func (r *pocketSendResponse) succeeded(i int) bool {
+	if i >= len(r.ActionResults) {
+		return false
+	}
+
+	result := bytes.TrimSpace(r.ActionResults[i])
+	return len(result) > 0 && !bytes.Equal(result, []byte("false")) && !bytes.Equal(result, []byte("null"))
+}
+
+// actionError return error message of i-th action
+func (r *pocketSendResponse) actionError(i int) string {
+	if i < len(r.ActionErrors) && r.ActionErrors[i] != nil && r.ActionErrors[i].Message != "" {
+		return r.ActionErrors[i].Message
+	}
+
+	return "action failed"
+}
+
+// pocketSend run actions with send api
+func pocketSend(ctx context.Context, accessToken string, actions []*pocketAction) (*pocketSendResponse, error) {
+	var resp pocketSendResponse
+	if err := pocketPost(ctx, "/send", &pocketSendRequest{
+		ConsumerKey: config.ConsumerKey(),
+		AccessToken: accessToken,
+		Actions:     actions,
+	}, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+type pocketGetRequest struct {
+	ConsumerKey string `json:"consumer_key"`
+	AccessToken string `json:"access_token"`
+	State       string `json:"state,omitempty"`
+	Favorite    string `json:"favorite,omitempty"`
+	Since       int64  `json:"since,omitempty"`
+	DetailType  string `json:"detailType"`
+}
+
+// pocketGetResponse response of get api, list is empty array if no articles
+type pocketGetResponse struct {
+	Status int             `json:"status"`
+	List   json.RawMessage `json:"list"`
+	Since  int64           `json:"since"` // server time to request changes since this response
+}
+
+// pocketGetResult articles and since of get api
+type pocketGetResult struct {
+	Articles map[string]*getpocket.Article
+	Since    int64
+}
+
+// pocketGet get articles with get api
+func pocketGet(ctx context.Context, accessToken string, req *pocketGetRequest) (*pocketGetResult, error) {
+	req.ConsumerKey = config.ConsumerKey()
+	req.AccessToken = accessToken
+	req.DetailType = "complete"
+
+	var resp pocketGetResponse
+	if err := pocketPost(ctx, "/get", req, &resp); err != nil {
+		return nil, err
+	}
+
+	result := &pocketGetResult{Articles: make(map[string]*getpocket.Article), Since: resp.Since}
+	if list := bytes.TrimSpace(resp.List); len(list) > 0 && list[0] == '{' {
+		if err := json.Unmarshal(list, &result.Articles); err != nil {
+			return nil, errors.Wrap(err, "decode articles")
+		}
+	}
+
+	return result, nil
+}
+
+// pocketPost post request as json to getpocket api and decode response to v
+// pocketStatusError error response of getpocket api
+type pocketStatusError struct {
+	Path       string
+	StatusCode int
+	Message    string // X-Error header
+}
+
+func (e *pocketStatusError) Error() string {
+	return fmt.Sprintf("%s failed with %d: %s", e.Path, e.StatusCode, e.Message)
+}
+
+func pocketPost(ctx context.Context, path string, body any, v any) error {
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pocketAPIURL+path, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &pocketStatusError{Path: path, StatusCode: resp.StatusCode, Message: resp.Header.Get("X-Error")}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,240 @@
+package pocket
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/whitekid/getpocket"
+	"github.com/whitekid/goxp/log"
+
+	"pocket-pick/config"
+	"pocket-pick/pkg/cache"
+)
+
+// favoritesSync favorite articles synced with pocket
+// it is kept beyond cache expiration, so it is also served as last known articles while pocket is not available
+type favoritesSync struct {
+	Since      int64                         `json:"since"`           // since of last sync response, pocket server time
+	FullSyncAt int64                         `json:"full_sync_at"`    // unix time of last full sync
+	Fence      int64                         `json:"fence,omitempty"` // fencing token of favorites lock it was written under
+	Articles   map[string]*getpocket.Article `json:"articles"`
+}
+
+// syncFavorites fetch favorite articles changed since last sync and merge them to synced articles
+// fetch all favorite articles if not synced yet or full sync interval passed
+// return cache.ErrLockLost if others synced under newer lock while lock expired
+func (s *pocketService) syncFavorites(ctx context.Context, accessToken string, lock *cache.Lock) (map[string]*getpocket.Article, error) {
+	state, err := s.loadFavoritesSync(ctx, accessToken)
+	if err != nil && err != cache.ErrNotExists {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	// since is taken from the response, so changes are not missed by clock skew with pocket
+	var since int64
+	if state == nil || time.Unix(state.FullSyncAt, 0).Add(config.FullSyncInterval()).Before(now) {
+		log.Debug("full sync favorite articles")
+
+		result, err := callPocket(ctx, func(ctx context.Context) (*pocketGetResult, error) {
+			return pocketGet(ctx, accessToken, &pocketGetRequest{Favorite: "1"})
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "get favorite artcles failed")
+		}
+
+		state = &favoritesSync{FullSyncAt: now.Unix(), Articles: result.Articles}
+		since = result.Since
+	} else {
+		log.Debugf("sync favorite articles since %s", time.Unix(state.Since, 0))
+
+		result, err := callPocket(ctx, func(ctx context.Context) (*pocketGetResult, error) {
+			return pocketGet(ctx, accessToken, &pocketGetRequest{State: "all", Since: state.Since})
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "get changed artcles failed")
+		}
+
+		mergeFavorites(state.Articles, result.Articles)
+		since = result.Since
+	}
+
+	if since != 0 {
+		state.Since = since
+	}
+	if err := s.saveFavoritesSync(ctx, accessToken, state, lock); err != nil {
+		if err == cache.ErrLockLost {
+			return nil, err
+		}
+		log.Errorf("fail to save favorites sync: %s", err)
+	}
+
+	return state.Articles, nil
+}
+
+// mergeFavorites apply changed articles to favorite articles
+// only unread favorites are kept, same as full sync which gets favorites of default unread state
+func mergeFavorites(articles map[string]*getpocket.Article, changes map[string]*getpocket.Article) {
+	for itemID, article := range changes {
+		if article.Status != articleStatusUnread || !isFavorite(article) {
+			delete(articles, itemID)
+			continue
+		}
+
+		articles[itemID] = article
+	}
+}
+
+func (s *pocketService) loadFavoritesSync(ctx context.Context, accessToken string) (*favoritesSync, error) {
+	state, err := s.syncCache.Get(ctx, s.keys.Key(ctx, accessToken, keyFavoritesSync))
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Articles == nil {
+		state.Articles = make(map[string]*getpocket.Article)
+	}
+
+	return state, nil
+}
+
+// saveFavoritesSync save state written under favorites lock
+// return cache.ErrLockLost if stored state was written under newer lock, so holder of expired lease does not overwrite it
+func (s *pocketService) saveFavoritesSync(ctx context.Context, accessToken string, state *favoritesSync, lock *cache.Lock) error {
+	stored, err := s.loadFavoritesSync(ctx, accessToken)
+	switch {
+	case err == nil:
+		if err := checkFence(stored.Fence, lock); err != nil {
+			return err
+		}
+	case err != cache.ErrNotExists:
+		return err
+	}
+
+	state.Fence = lock.Token()
+	return s.syncCache.Set(ctx, s.keys.Key(ctx, accessToken, keyFavoritesSync), state)
+}
+
+// checkFence return cache.ErrLockLost if value was written under newer lock than lock
+// the check is done before write, so it does not close the window of a write racing with it
+func checkFence(fence int64, lock *cache.Lock) error {
+	if fence > lock.Token() {
+		return cache.ErrLockLost
+	}
+	return nil
+}
+
+// forgetArticles remove articles from cached favorites and synced articles, so removed articles are not picked
+// cached favorites get a new version, so index of the user is rebuilt
+func (s *pocketService) forgetArticles(ctx context.Context, accessToken string, itemIDs ...string) error {
+	if len(itemIDs) == 0 {
+		return nil
+	}
+	defer s.indexes.Remove(accessToken)
+
+	// favorites sync writes the same articles, so they are not updated at the same time
+	lock, err := s.locker.Lock(ctx, s.lockName(accessToken, keyFavorites), favoritesLockTTL)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock(context.WithoutCancel(ctx))
+
+	state, err := s.loadFavoritesSync(ctx, accessToken)
+	switch {
+	case err == nil:
+		if removeFavorites(state.Articles, itemIDs) {
+			if err := s.saveFavoritesSync(ctx, accessToken, state, lock); err != nil {
+				return err
+			}
+		}
+	case err != cache.ErrNotExists:
+		return err
+	}
+
+	key := s.keys.Key(ctx, accessToken, keyFavorites)
+	list, err := s.favoritesCache.Get(ctx, key)
+	if err != nil {
+		if err == cache.ErrNotExists {
+			return nil
+		}
+		return err
+	}
+
+	if err := checkFence(list.Fence, lock); err != nil {
+		return err
+	}
+
+	if !removeFavorites(list.Articles, itemIDs) {
+		return nil
+	}
+	list.Version = strconv.FormatInt(time.Now().UnixNano(), 36)
+	list.Fence = lock.Token()
+
+	// keep expiration of cached favorites, they are refreshed as scheduled
+	if err := s.favoritesCache.Replace(ctx, key, list); err != nil {
+		if err == cache.ErrNotExists {
+			return nil
+		}
+		return err
+	}
+
+	versionKey := s.keys.Key(ctx, accessToken, keyFavoritesVersion)
+	ttl, err := s.versionCache.TTL(ctx, versionKey)
+	if err != nil {
+		if err == cache.ErrNotExists {
+			return nil
+		}
+		return err
+	}
+	return s.versionCache.Set(ctx, versionKey, list.Version, cache.WithExpire(ttl))
+}
+
+// removeFavorites remove articles from favorite articles, return true if any of them removed
+func removeFavorites(articles map[string]*getpocket.Article, itemIDs []string) bool {
+	removed := false
+	for _, itemID := range itemIDs {
+		if _, ok := articles[itemID]; ok {
+			delete(articles, itemID)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// resyncFavorites drop cached favorites and force full sync on next pick, so restored articles are picked again
+// synced articles are kept to serve them while pocket is not available
+func (s *pocketService) resyncFavorites(ctx context.Context, accessToken string) error {
+	return s.dropFavorites(ctx, accessToken, true)
+}
+
+// invalidateFavorites drop cached favorites, so articles changed in pocket are fetched by delta sync on next pick
+func (s *pocketService) invalidateFavorites(ctx context.Context, accessToken string) error {
+	return s.dropFavorites(ctx, accessToken, false)
+}
+
+func (s *pocketService) dropFavorites(ctx context.Context, accessToken string, fullSync bool) error {
+	defer s.indexes.Remove(accessToken)
+
+	lock, err := s.locker.Lock(ctx, s.lockName(accessToken, keyFavorites), favoritesLockTTL)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock(context.WithoutCancel(ctx))
+
+	if fullSync {
+		state, err := s.loadFavoritesSync(ctx, accessToken)
+		switch {
+		case err == nil:
+			state.FullSyncAt = 0
+			if err := s.saveFavoritesSync(ctx, accessToken, state, lock); err != nil {
+				return err
+			}
+		case err != cache.ErrNotExists:
+			return err
+		}
+	}
+
+	return s.favoritesCache.Delete(ctx, s.keys.Key(ctx, accessToken, keyFavorites), s.keys.Key(ctx, accessToken, keyFavoritesVersion))
+}
@@ -0,0 +1,208 @@
+package pocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/whitekid/getpocket"
+	"golang.org/x/exp/maps"
+
+	"pocket-pick/pkg/cache"
+)
+
+func TestMergeFavorites(t *testing.T) {
+	articles := map[string]*getpocket.Article{
+		"1": {ItemID: "1", Favorite: "1", Status: "0"},
+		"2": {ItemID: "2", Favorite: "1", Status: "0"},
+		"3": {ItemID: "3", Favorite: "1", Status: "0"},
+		"6": {ItemID: "6", Favorite: "1", Status: "0"},
+	}
+
+	changes := map[string]*getpocket.Article{
+		"1": {ItemID: "1", Favorite: "1", Status: "0", ResolvedTitle: "updated"},
+		"2": {ItemID: "2", Favorite: "1", Status: articleStatusDeleted},
+		"3": {ItemID: "3", Favorite: "0", Status: "0"},
+		"4": {ItemID: "4", Favorite: "1", Status: "0"},
+		"5": {ItemID: "5", Favorite: "0", Status: "0"},
+		"6": {ItemID: "6", Favorite: "1", Status: articleStatusArchived},
+		"7": {ItemID: "7", Favorite: "1", Status: articleStatusArchived},
+	}
+
+	mergeFavorites(articles, changes)
+
+	keys := maps.Keys(articles)
+	require.ElementsMatch(t, []string{"1", "4"}, keys)
+	require.Equal(t, "updated", articles["1"].ResolvedTitle)
+}
+
+func TestSyncFavoritesSince(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	var requests []*pocketGetRequest
+	responses := []string{
+		`{"status": 1, "since": 1700000000, "list": {"1": {"item_id": "1", "favorite": "1"}}}`,
+		`{"status": 1, "since": 1700000100, "list": []}`,
+	}
+	newPocketStub(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/get", r.URL.Path)
+
+		var req pocketGetRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		require.Equal(t, "token", req.AccessToken)
+		require.Equal(t, "complete", req.DetailType)
+		requests = append(requests, &req)
+
+		w.Write([]byte(responses[len(requests)-1]))
+	})
+
+	lock, err := s.locker.TryLock(ctx, s.lockName("token", keyFavorites), time.Minute)
+	require.NoError(t, err)
+
+	articles, err := s.syncFavorites(ctx, "token", lock)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1"}, maps.Keys(articles))
+	require.Equal(t, "1", requests[0].Favorite, "full sync")
+
+	articles, err = s.syncFavorites(ctx, "token", lock)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1"}, maps.Keys(articles))
+	require.Equal(t, int64(1700000000), requests[1].Since, "since of last response should be requested")
+
+	state, err := s.loadFavoritesSync(ctx, "token")
+	require.NoError(t, err)
+	require.Equal(t, int64(1700000100), state.Since)
+}
+
+func newTestService(t *testing.T) *pocketService {
+	c := cache.NewBigCache(context.Background())
+	keys := cache.NewKeyBuilder("test", 1, "secret")
+
+	locker := cache.NewLocalLocker()
+
+	return &pocketService{
+		locker:         locker,
+		keys:           keys,
+		indexes:        cache.NewLRU[string, *articleIndex](indexCacheSize, indexTTL),
+		favoritesCache: cache.NewTyped[*favoritesList](c, cache.JSON),
+		versionCache:   cache.NewTyped[string](c, cache.JSON),
+		syncCache:      cache.NewTyped[*favoritesSync](c, cache.JSON),
+		trash: NewTrash(newCacheTrashStore(c, cache.JSON, keys, locker)).WithLocker(locker, func(accessToken string) string {
+			return userLockName(keys, accessToken, keyTrash)
+		}),
+	}
+}
+
+func TestForgetArticles(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	articles := func() map[string]*getpocket.Article {
+		return map[string]*getpocket.Article{
+			"1": {ItemID: "1", Favorite: "1"},
+			"2": {ItemID: "2", Favorite: "1"},
+		}
+	}
+
+	now := time.Now().Unix()
+	require.NoError(t, s.syncCache.Set(ctx, s.keys.Key(ctx, "token", keyFavoritesSync), &favoritesSync{Since: now, FullSyncAt: now, Articles: articles()}))
+	require.NoError(t, s.favoritesCache.Set(ctx, s.keys.Key(ctx, "token", keyFavorites), &favoritesList{Version: "v1", Articles: articles()}, cache.WithExpire(time.Hour)))
+	require.NoError(t, s.versionCache.Set(ctx, s.keys.Key(ctx, "token", keyFavoritesVersion), "v1", cache.WithExpire(time.Minute)))
+	s.indexes.Add("token", newArticleIndex("v1", articles()))
+
+	require.NoError(t, s.forgetArticles(ctx, "token", "1", "3"))
+
+	state, err := s.loadFavoritesSync(ctx, "token")
+	require.NoError(t, err)
+	require.Equal(t, []string{"2"}, maps.Keys(state.Articles))
+
+	idx, degraded, err := s.favoritesIndex(ctx, "token")
+	require.NoError(t, err)
+	require.False(t, degraded)
+	require.NotEqual(t, "v1", idx.version, "index should be rebuilt")
+	require.Len(t, idx.articles, 1)
+	require.Equal(t, "2", idx.pick("", "").ItemID)
+
+	ttl, err := s.favoritesCache.TTL(ctx, s.keys.Key(ctx, "token", keyFavorites))
+	require.NoError(t, err)
+	require.Greater(t, ttl, time.Minute, "expiration should be kept")
+
+	// resync drop cached favorites and force full sync
+	require.NoError(t, s.resyncFavorites(ctx, "token"))
+	_, err = s.cachedFavorites(ctx, "token")
+	require.ErrorIs(t, err, cache.ErrNotExists)
+
+	state, err = s.loadFavoritesSync(ctx, "token")
+	require.NoError(t, err)
+	require.Zero(t, state.FullSyncAt)
+	require.Len(t, state.Articles, 1, "synced articles are kept for degraded mode")
+
+	// nothing cached
+	require.NoError(t, s.forgetArticles(ctx, "other-token", "1"))
+}
+
+func TestInvalidateFavorites(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	articles := map[string]*getpocket.Article{"1": {ItemID: "1", Favorite: "1", Tags: map[string]getpocket.Tag{"old": {}}}}
+	now := time.Now().Unix()
+	require.NoError(t, s.syncCache.Set(ctx, s.keys.Key(ctx, "token", keyFavoritesSync), &favoritesSync{Since: now, FullSyncAt: now, Articles: articles}))
+	require.NoError(t, s.favoritesCache.Set(ctx, s.keys.Key(ctx, "token", keyFavorites), &favoritesList{Version: "v1", Articles: articles}, cache.WithExpire(time.Hour)))
+	s.indexes.Add("token", newArticleIndex("v1", articles))
+
+	require.NoError(t, s.invalidateFavorites(ctx, "token"))
+
+	_, err := s.cachedFavorites(ctx, "token")
+	require.ErrorIs(t, err, cache.ErrNotExists)
+	_, ok := s.indexes.Get("token")
+	require.False(t, ok)
+
+	state, err := s.loadFavoritesSync(ctx, "token")
+	require.NoError(t, err)
+	require.Equal(t, now, state.FullSyncAt, "changes are fetched by delta sync")
+}
+
+func TestFavoritesFencing(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	expired, err := s.locker.TryLock(ctx, s.lockName("token", keyFavorites), 50*time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(100 * time.Millisecond)
+
+	lock, err := s.locker.TryLock(ctx, s.lockName("token", keyFavorites), time.Minute)
+	require.NoError(t, err, "expired lease should be taken")
+	require.NoError(t, s.saveFavoritesSync(ctx, "token", &favoritesSync{FullSyncAt: 1}, lock))
+
+	require.ErrorIs(t, s.saveFavoritesSync(ctx, "token", &favoritesSync{FullSyncAt: 2}, expired), cache.ErrLockLost, "write under older lock should be rejected")
+	state, err := s.loadFavoritesSync(ctx, "token")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), state.FullSyncAt)
+	require.Equal(t, lock.Token(), state.Fence)
+
+	key := s.keys.Key(ctx, "token", keyFavorites)
+	require.NoError(t, s.favoritesCache.Set(ctx, key, &favoritesList{Version: "v1", Fence: lock.Token(), Articles: map[string]*getpocket.Article{
+		"1": {ItemID: "1", Favorite: "1"},
+	}}, cache.WithExpire(time.Hour)))
+	require.NoError(t, lock.Unlock(ctx))
+
+	require.NoError(t, s.forgetArticles(ctx, "token", "1"), "forget takes a newer lock")
+	list, err := s.favoritesCache.Get(ctx, key)
+	require.NoError(t, err)
+	require.Empty(t, list.Articles)
+	require.Greater(t, list.Fence, lock.Token())
+}
+
+func TestFavoritesLoadedAfter(t *testing.T) {
+	now := time.Now()
+
+	require.True(t, (&favoritesList{Version: strconv.FormatInt(now.Add(time.Second).UnixNano(), 36)}).loadedAfter(now))
+	require.False(t, (&favoritesList{Version: strconv.FormatInt(now.Add(-time.Second).UnixNano(), 36)}).loadedAfter(now))
+	require.False(t, (&favoritesList{}).loadedAfter(now), "degraded list has no version")
+}
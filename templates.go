@@ -0,0 +1,105 @@
+package pocket
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+var templates = template.Must(template.New("").Parse(`
+{{define "undo-banner"}}<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>pocket-pick</title></head>
+<body>
+<div id="banner">
+  Article {{.ItemID}} will be deleted at {{.DeleteAt.Format "2006-01-02 15:04:05 MST"}}.
+  <button id="undo">Undo</button>
+</div>
+<script>
+document.getElementById("undo").onclick = async () => {
+  const resp = await fetch("/api/v1/trash/{{.ItemID}}/undo", {method: "POST"});
+  document.getElementById("banner").textContent = resp.ok ? "Restored." : "Undo failed: " + (await resp.json()).message;
+};
+</script>
+</body>
+</html>
+{{end}}
+
+{{define "preparing"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8"><title>pocket-pick</title>
+<noscript><meta http-equiv="refresh" content="3;url={{.Next}}"></noscript>
+</head>
+<body>
+<p>Preparing your library...</p>
+<script>
+(async () => {
+  for (;;) {
+    await new Promise((resolve) => setTimeout(resolve, 1000));
+    const resp = await fetch("/api/v1/library/status");
+    if (!resp.ok || (await resp.json()).ready) break;
+  }
+  window.location.replace({{.Next}});
+})();
+</script>
+</body>
+</html>
+{{end}}
+
+{{define "settings-bookmarklet"}}<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>pocket-pick bookmarklet</title></head>
+<body>
+<p>Drag a link to your bookmarks bar and click it to save the current page to pocket.</p>
+<ul>
+  <li><a href="{{.Save}}">Save to pocket</a></li>
+  <li><a href="{{.SaveFavorite}}">Save to pocket as favorite</a></li>
+</ul>
+</body>
+</html>
+{{end}}
+
+{{define "bookmarklet"}}<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>pocket-pick</title></head>
+<body>
+<form method="post" action="/bookmarklet">
+  <input type="hidden" name="_csrf" value="{{.CSRF}}">
+  <input type="hidden" name="url" value="{{.URL}}">
+  <input type="hidden" name="title" value="{{.Title}}">
+  <input type="hidden" name="favorite" value="{{.Favorite}}">
+  <p>Save <a href="{{.URL}}">{{.Title}}</a> to pocket{{if .Favorite}} as favorite{{end}}?</p>
+  <button type="submit">Save</button>
+</form>
+</body>
+</html>
+{{end}}
+
+{{define "bookmarklet-saved"}}<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>pocket-pick</title></head>
+<body>
+<p>{{.Status}}</p>
+{{if .Saved}}<script>setTimeout(() => window.close(), 1000);</script>{{end}}
+</body>
+</html>
+{{end}}
+`))
+
+// render render html template
+func render(c echo.Context, code int, name string, data any) error {
+	buf := new(bytes.Buffer)
+	if err := templates.ExecuteTemplate(buf, name, data); err != nil {
+		return err
+	}
+
+	return c.HTMLBlob(code, buf.Bytes())
+}
+
+// renderUndoBanner render banner to undo article deletion
+func renderUndoBanner(c echo.Context, entry *TrashEntry) error {
+	return render(c, http.StatusAccepted, "undo-banner", entry)
+}
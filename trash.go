@@ -0,0 +1,391 @@
+package pocket
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/whitekid/getpocket"
+	"github.com/whitekid/goxp/log"
+	"golang.org/x/exp/maps"
+
+	"pocket-pick/config"
+	"pocket-pick/pkg/cache"
+)
+
+var (
+	ErrNotInTrash    = errors.New("article not in trash")
+	ErrCannotRestore = errors.New("article deleted without url, can not be restored")
+)
+
+// TrashEntry article deleted in soft-delete mode
+// the article is deleted from pocket when DeleteAt passed
+type TrashEntry struct {
+	ItemID   string    `json:"item_id"`
+	URL      string    `json:"url,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+	Favorite bool      `json:"favorite"`
+	DeleteAt time.Time `json:"delete_at"`
+	Deleted  bool      `json:"deleted"` // true if already deleted from pocket
+}
+
+// TrashStore persist trash entries of user
+// entries are kept until they are saved again, so the store should not evict them
+type TrashStore interface {
+	Load(ctx context.Context, accessToken string) ([]*TrashEntry, error)
+	Save(ctx context.Context, accessToken string, entries []*TrashEntry) error
+
+	// return access tokens of users who have entries, to flush their trash after restart
+	Users(ctx context.Context) ([]string, error)
+}
+
+// Trash queue article deletions for grace period
+type Trash struct {
+	store  TrashStore
+	grace  time.Duration // wait before delete from pocket
+	retain time.Duration // keep deleted entries to restore them by undo
+
+	locker   cache.Locker                    // lock flush of user shared with other processes, nil if not shared
+	lockName func(accessToken string) string // name of flush lock of user
+
+	mu sync.Mutex
+}
+
+// NewTrash create new trash with grace period and retention from config
+func NewTrash(store TrashStore) *Trash {
+	return &Trash{
+		store:  store,
+		grace:  config.DeleteGracePeriod(),
+		retain: config.TrashRetention(),
+	}
+}
+
+// WithLocker lock flush of user with locker, so trash shared by replicas and commands is flushed by one of them at a time
+func (t *Trash) WithLocker(locker cache.Locker, lockName func(accessToken string) string) *Trash {
+	t.locker = locker
+	t.lockName = lockName
+	return t
+}
+
+// Enabled return true if soft delete enabled
+func (t *Trash) Enabled() bool { return t.grace > 0 }
+
+// Put queue articles to delete; itemIDs without article detail are queued without url and can not be restored after deletion.
+func (t *Trash) Put(ctx context.Context, accessToken string, articles map[string]*getpocket.Article, itemIDs ...string) ([]*TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries, err := t.store.Load(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteAt := time.Now().Add(t.grace)
+	added := make([]*TrashEntry, 0, len(itemIDs))
+	for _, itemID := range itemIDs {
+		if slices.ContainsFunc(entries, func(e *TrashEntry) bool { return e.ItemID == itemID }) {
+			continue
+		}
+
+		entry := &TrashEntry{ItemID: itemID, DeleteAt: deleteAt}
+		if article, ok := articles[itemID]; ok {
+			entry.URL = articleURL(article)
+			entry.Tags = articleTags(article)
+			entry.Favorite = isFavorite(article)
+		}
+
+		entries = append(entries, entry)
+		added = append(added, entry)
+	}
+
+	if err := t.store.Save(ctx, accessToken, entries); err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+// List return trash entries of user
+func (t *Trash) List(ctx context.Context, accessToken string) ([]*TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.store.Load(ctx, accessToken)
+}
+
+// Users return access tokens of users who have articles in trash
+func (t *Trash) Users(ctx context.Context) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.store.Users(ctx)
+}
+
+// Undo cancel deletion of article
+// if the article is already deleted from pocket, add it again with its tags and favorite flag; the added article has a new item id
+func (t *Trash) Undo(ctx context.Context, accessToken string, itemID string) (*TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries, err := t.store.Load(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := slices.IndexFunc(entries, func(e *TrashEntry) bool { return e.ItemID == itemID })
+	if idx == -1 {
+		return nil, ErrNotInTrash
+	}
+
+	entry := entries[idx]
+	if entry.Deleted {
+		if entry.URL == "" {
+			return nil, ErrCannotRestore
+		}
+
+		api := getpocket.New(config.ConsumerKey(), accessToken)
+		article, err := callPocket(ctx, api.Add(entry.URL).Tags(entry.Tags...).Do)
+		if err != nil {
+			return nil, errors.Wrapf(err, "articles.Add(%s)", entry.URL)
+		}
+
+		if entry.Favorite {
+			if err := doModify(ctx, api.Modify().Favorite(article.ItemID)); err != nil {
+				return nil, errors.Wrapf(err, "articles.Favorite(%s)", article.ItemID)
+			}
+		}
+	}
+
+	if err := t.store.Save(ctx, accessToken, slices.Delete(entries, idx, idx+1)); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+// Flush delete articles whose grace period passed from pocket and drop entries older than retention
+// return number of entries still in trash, cache.ErrLocked if trash of the user is being flushed by others
+func (t *Trash) Flush(ctx context.Context, accessToken string) (int, error) {
+	if t.locker != nil {
+		lock, err := t.locker.TryLock(ctx, t.lockName(accessToken), trashFlushInterval)
+		if err != nil {
+			return 0, err
+		}
+		defer lock.Unlock(context.WithoutCancel(ctx))
+
+		var cancel context.CancelFunc
+		ctx, cancel = lock.KeepAlive(ctx)
+		defer cancel()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entries, err := t.store.Load(ctx, accessToken)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var itemIDs []string
+	for _, entry := range entries {
+		if !entry.Deleted && entry.DeleteAt.Before(now) {
+			itemIDs = append(itemIDs, entry.ItemID)
+		}
+	}
+
+	if len(itemIDs) > 0 {
+		log.Infof("deleting expired trash: %v", itemIDs)
+		if err := doModify(ctx, getpocket.New(config.ConsumerKey(), accessToken).Modify().Delete(itemIDs...)); err != nil {
+			return len(entries), errors.Wrapf(err, "articles.Delete(%s)", itemIDs)
+		}
+
+		for _, entry := range entries {
+			if slices.Contains(itemIDs, entry.ItemID) {
+				entry.Deleted = true
+			}
+		}
+	}
+
+	entries = slices.DeleteFunc(entries, func(e *TrashEntry) bool { return e.Deleted && e.DeleteAt.Add(t.retain).Before(now) })
+	if err := t.store.Save(ctx, accessToken, entries); err != nil {
+		return len(entries), err
+	}
+
+	return len(entries), nil
+}
+
+// trashUsersLockTTL lease of index of users with trash, it is held only while the index is written
+const trashUsersLockTTL = 10 * time.Second
+
+// cacheTrashStore store trash entries to cache, which should not evict them, e.g. redis
+// access tokens of users with entries are kept in an index to flush their trash after restart, so the cache should be encrypted
+type cacheTrashStore struct {
+	entries *cache.Typed[[]*TrashEntry]
+	users   *cache.Typed[[]string] // access tokens of users who have entries
+	keys    *cache.KeyBuilder
+	locker  cache.Locker // lock of the index shared by replicas
+}
+
+var _ TrashStore = (*cacheTrashStore)(nil)
+
+func newCacheTrashStore(c cache.Interface, serializer cache.Serializer, keys *cache.KeyBuilder, locker cache.Locker) *cacheTrashStore {
+	return &cacheTrashStore{
+		entries: cache.NewTyped[[]*TrashEntry](c, serializer),
+		users:   cache.NewTyped[[]string](c, serializer),
+		keys:    keys,
+		locker:  locker,
+	}
+}
+
+func (s *cacheTrashStore) Load(ctx context.Context, accessToken string) ([]*TrashEntry, error) {
+	entries, err := s.entries.Get(ctx, s.keys.Key(ctx, accessToken, keyTrash))
+	if err != nil {
+		if err == cache.ErrNotExists {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (s *cacheTrashStore) Save(ctx context.Context, accessToken string, entries []*TrashEntry) error {
+	key := s.keys.Key(ctx, accessToken, keyTrash)
+	if len(entries) == 0 {
+		if err := s.entries.Delete(ctx, key); err != nil {
+			return err
+		}
+	} else if err := s.entries.Set(ctx, key, entries); err != nil {
+		return err
+	}
+
+	return s.updateUsers(ctx, accessToken, len(entries) > 0)
+}
+
+// Users read index of users who have entries
+func (s *cacheTrashStore) Users(ctx context.Context) ([]string, error) {
+	users, err := s.users.Get(ctx, s.usersKey())
+	if err != nil && err != cache.ErrNotExists {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// updateUsers add user to index if the user has entries, otherwise remove the user
+// index is shared by replicas, so it is changed under lock
+func (s *cacheTrashStore) updateUsers(ctx context.Context, accessToken string, pending bool) error {
+	users, err := s.Users(ctx)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(users, accessToken) == pending {
+		return nil
+	}
+
+	lock, err := s.locker.Lock(ctx, s.keys.Root()+"lock/"+keyTrashUsers, trashUsersLockTTL)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock(context.WithoutCancel(ctx))
+
+	if users, err = s.Users(ctx); err != nil {
+		return err
+	}
+
+	users = slices.DeleteFunc(users, func(user string) bool { return user == accessToken })
+	if pending {
+		users = append(users, accessToken)
+	}
+
+	if len(users) == 0 {
+		return s.users.Delete(ctx, s.usersKey())
+	}
+	return s.users.Set(ctx, s.usersKey(), users)
+}
+
+func (s *cacheTrashStore) usersKey() string { return s.keys.Root() + keyTrashUsers }
+
+// NewFileTrashStore return trash store which save entries as json file
+func NewFileTrashStore(path string) TrashStore {
+	return &fileTrashStore{path: path}
+}
+
+type fileTrashStore struct {
+	path string
+}
+
+var _ TrashStore = (*fileTrashStore)(nil)
+
+func (s *fileTrashStore) load() (map[string][]*TrashEntry, error) {
+	users := make(map[string][]*TrashEntry)
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return users, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, errors.Wrapf(err, "json decode failed: %s", s.path)
+	}
+
+	return users, nil
+}
+
+func (s *fileTrashStore) Load(ctx context.Context, accessToken string) ([]*TrashEntry, error) {
+	users, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return users[accessToken], nil
+}
+
+func (s *fileTrashStore) Users(ctx context.Context) ([]string, error) {
+	users, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return maps.Keys(users), nil
+}
+
+func (s *fileTrashStore) Save(ctx context.Context, accessToken string, entries []*TrashEntry) error {
+	users, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		delete(users, accessToken)
+	} else {
+		users[accessToken] = entries
+	}
+
+	buf, err := json.Marshal(users)
+	if err != nil {
+		return errors.Wrap(err, "json encode failed")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+
+	// write to temp file and rename, so entries are not lost by partial write
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,139 @@
+package pocket
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/whitekid/getpocket"
+
+	"pocket-pick/pkg/cache"
+)
+
+func TestTrash(t *testing.T) {
+	type args struct {
+		store TrashStore
+	}
+	tests := [...]struct {
+		name string
+		args args
+	}{
+		{"cache", args{newCacheTrashStore(cache.NewBigCache(context.Background()), cache.JSON, cache.NewKeyBuilder("test", 1, "secret"), cache.NewLocalLocker())}},
+		{"file", args{NewFileTrashStore(filepath.Join(t.TempDir(), "trash.json"))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			trash := &Trash{store: tt.args.store, grace: time.Minute, retain: time.Hour}
+			require.True(t, trash.Enabled())
+
+			articles := map[string]*getpocket.Article{
+				"1234": {
+					ItemID:      "1234",
+					ResolvedURL: "https://example.com/1234",
+					Favorite:    "1",
+					Tags:        map[string]getpocket.Tag{"go": {ItemID: "1234", Tag: "go"}},
+				},
+			}
+
+			entries, err := trash.Put(ctx, "token", articles, "1234", "5678")
+			require.NoError(t, err)
+			require.Len(t, entries, 2)
+			require.Equal(t, "https://example.com/1234", entries[0].URL)
+			require.Equal(t, []string{"go"}, entries[0].Tags)
+			require.True(t, entries[0].Favorite)
+			require.Empty(t, entries[1].URL, "article detail unknown")
+
+			// put again does not duplicate
+			entries, err = trash.Put(ctx, "token", articles, "1234")
+			require.NoError(t, err)
+			require.Empty(t, entries)
+
+			users, err := trash.Users(ctx)
+			require.NoError(t, err)
+			require.Equal(t, []string{"token"}, users)
+
+			// nothing expired yet
+			remains, err := trash.Flush(ctx, "token")
+			require.NoError(t, err)
+			require.Equal(t, 2, remains)
+
+			entry, err := trash.Undo(ctx, "token", "1234")
+			require.NoError(t, err)
+			require.Equal(t, "1234", entry.ItemID)
+
+			_, err = trash.Undo(ctx, "token", "1234")
+			require.ErrorIs(t, err, ErrNotInTrash)
+
+			entries, err = trash.List(ctx, "token")
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			require.Equal(t, "5678", entries[0].ItemID)
+
+			entries, err = trash.List(ctx, "other-token")
+			require.NoError(t, err)
+			require.Empty(t, entries)
+		})
+	}
+}
+
+func TestTrashPreviousVersion(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewBigCache(ctx)
+	keys := cache.NewKeyBuilder("test", 2, "secret")
+	store := newCacheTrashStore(c, cache.JSON, keys.WithPrevious(c, 1), cache.NewLocalLocker())
+
+	// trash of version 1, key had no hash tag
+	hash := strings.Trim(strings.TrimPrefix(keys.Prefix("token"), "test:v2:"), "{}:")
+	previous := "test:v1:" + hash + ":" + keyTrash
+	require.NoError(t, store.entries.Set(ctx, previous, []*TrashEntry{{ItemID: "1234"}}))
+
+	entries, err := store.Load(ctx, "token")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.False(t, c.Has(ctx, previous), "trash should be moved to current key")
+}
+
+func TestCacheTrashStoreUsers(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewBigCache(ctx)
+	store := newCacheTrashStore(c, cache.JSON, cache.NewKeyBuilder("test", 1, "secret"), cache.NewLocalLocker())
+
+	require.NoError(t, store.Save(ctx, "token", []*TrashEntry{{ItemID: "1234"}}))
+	require.NoError(t, store.Save(ctx, "other-token", []*TrashEntry{{ItemID: "5678"}}))
+	require.NoError(t, store.Save(ctx, "token", []*TrashEntry{{ItemID: "1234"}, {ItemID: "4321"}}))
+
+	users, err := store.Users(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"token", "other-token"}, users)
+
+	record, err := c.Get(ctx, store.keys.Key(ctx, "token", keyTrash))
+	require.NoError(t, err)
+	require.NotContains(t, string(record), "token", "access token should be kept only in index")
+
+	require.NoError(t, store.Save(ctx, "token", nil))
+	require.NoError(t, store.Save(ctx, "other-token", nil))
+	users, err = store.Users(ctx)
+	require.NoError(t, err)
+	require.Empty(t, users)
+	require.False(t, c.Has(ctx, store.usersKey()), "empty index should be deleted")
+}
+
+func TestFlushUserTrashLocked(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	lock, err := s.locker.TryLock(ctx, s.lockName("token", keyTrash), time.Minute)
+	require.NoError(t, err)
+
+	_, err = s.trash.Flush(ctx, "token")
+	require.ErrorIs(t, err, cache.ErrLocked, "trash flushed by other replica should be skipped")
+
+	require.NoError(t, lock.Unlock(ctx))
+	remains, err := s.trash.Flush(ctx, "token")
+	require.NoError(t, err)
+	require.Zero(t, remains)
+}
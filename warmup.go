@@ -0,0 +1,90 @@
+package pocket
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/whitekid/goxp/log"
+)
+
+const (
+	warmUpWait    = 2 * time.Second // wait for prefetch after login, before showing preparing page
+	warmUpTimeout = 5 * time.Minute
+)
+
+// LibraryStatus status of favorites of user, polled by preparing page
+type LibraryStatus struct {
+	Ready bool `json:"ready"`
+}
+
+// warmUp prefetch favorites of user in background, return channel closed when done
+// prefetch of the user already running here or in other replicas is shared by loader of favorites and its lock
+// warm up lock is held while loading, so replicas report the library is not ready
+// errors are logged as the next pick loads favorites again
+func (s *pocketService) warmUp(ctx context.Context, accessToken string) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), warmUpTimeout)
+		defer cancel()
+
+		// warm up of others holds the lock, favorites loaded by them are shared
+		if lock, err := s.locker.TryLock(ctx, s.lockName(accessToken, keyWarmUp), warmUpTimeout); err == nil {
+			defer lock.Unlock(context.WithoutCancel(ctx))
+		}
+
+		started := time.Now()
+		idx, _, err := s.favoritesIndex(ctx, accessToken)
+		if err != nil {
+			log.Warnf("warm up failed: %s", err)
+			return
+		}
+		log.Debugf("warm up %d articles in %s", len(idx.articles), time.Since(started))
+	}()
+
+	return done
+}
+
+// libraryReady return true if favorites of user are loaded, or no warm up loads them so pick loads them by itself
+// index in memory and small version key are checked first, so picks do not read cached favorites
+// warm up state is read from shared lock, so any replica answers the same while others prefetch
+func (s *pocketService) libraryReady(ctx context.Context, accessToken string) (bool, error) {
+	if _, ok := s.indexes.Get(accessToken); ok {
+		return true, nil
+	}
+
+	if s.versionCache.Has(ctx, s.keys.Key(ctx, accessToken, keyFavoritesVersion)) {
+		return true, nil
+	}
+
+	warming, err := s.locker.Held(ctx, s.lockName(accessToken, keyWarmUp))
+	if err != nil {
+		return false, err
+	}
+
+	return !warming, nil
+}
+
+// report whether favorites of user are ready to pick
+func (s *pocketService) handleGetLibraryStatus(c echo.Context) error {
+	accessToken, err := s.apiAccessToken(c)
+	if err != nil {
+		return err
+	}
+
+	ready, err := s.libraryReady(c.Request().Context(), accessToken)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, &LibraryStatus{Ready: ready})
+}
+
+// renderPreparing render page which polls library status and moves to next when ready
+func renderPreparing(c echo.Context, next string) error {
+	return render(c, http.StatusAccepted, "preparing", map[string]string{"Next": next})
+}
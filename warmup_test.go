@@ -0,0 +1,88 @@
+package pocket
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/whitekid/getpocket"
+
+	"pocket-pick/pkg/cache"
+)
+
+func TestWarmUp(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	articles := map[string]*getpocket.Article{"1": {ItemID: "1", Favorite: "1"}}
+	require.NoError(t, s.favoritesCache.Set(ctx, s.keys.Key(ctx, "token", keyFavorites), &favoritesList{Version: "v1", Articles: articles}, cache.WithExpire(time.Hour)))
+	require.NoError(t, s.versionCache.Set(ctx, s.keys.Key(ctx, "token", keyFavoritesVersion), "v1", cache.WithExpire(time.Hour)))
+
+	select {
+	case <-s.warmUp(ctx, "token"):
+	case <-time.After(time.Second):
+		require.Fail(t, "warm up should be done")
+	}
+	warming, err := s.locker.Held(ctx, s.lockName("token", keyWarmUp))
+	require.NoError(t, err)
+	require.False(t, warming, "warm up lock should be released")
+
+	ready, err := s.libraryReady(ctx, "token")
+	require.NoError(t, err)
+	require.True(t, ready)
+
+	idx, ok := s.indexes.Get("token")
+	require.True(t, ok, "index should be loaded by warm up")
+	require.Equal(t, "v1", idx.version)
+}
+
+func TestLibraryReady(t *testing.T) {
+	ctx := context.Background()
+	s := newTestService(t)
+
+	// other replica prefetch favorites, state is shared by lock and cache
+	lock, err := s.locker.TryLock(ctx, s.lockName("token", keyWarmUp), time.Minute)
+	require.NoError(t, err)
+
+	ready, err := s.libraryReady(ctx, "token")
+	require.NoError(t, err)
+	require.False(t, ready, "favorites are being loaded")
+
+	require.NoError(t, s.versionCache.Set(ctx, s.keys.Key(ctx, "token", keyFavoritesVersion), "v1", cache.WithExpire(time.Hour)))
+	ready, err = s.libraryReady(ctx, "token")
+	require.NoError(t, err)
+	require.True(t, ready, "favorites are cached")
+
+	// index in memory
+	lock2, err := s.locker.TryLock(ctx, s.lockName("indexed", keyWarmUp), time.Minute)
+	require.NoError(t, err)
+	defer lock2.Unlock(ctx)
+	s.indexes.Add("indexed", newArticleIndex("v1", nil))
+	ready, err = s.libraryReady(ctx, "indexed")
+	require.NoError(t, err)
+	require.True(t, ready, "favorites are indexed")
+
+	// favorites lock is held by delete or undo, not by warm up
+	favoritesLock, err := s.locker.TryLock(ctx, s.lockName("other", keyFavorites), time.Minute)
+	require.NoError(t, err)
+	defer favoritesLock.Unlock(ctx)
+	ready, err = s.libraryReady(ctx, "other")
+	require.NoError(t, err)
+	require.True(t, ready, "pick loads favorites by itself")
+
+	// load failed, pick loads favorites by itself
+	require.NoError(t, lock.Unlock(ctx))
+	require.NoError(t, s.versionCache.Delete(ctx, s.keys.Key(ctx, "token", keyFavoritesVersion)))
+	ready, err = s.libraryReady(ctx, "token")
+	require.NoError(t, err)
+	require.True(t, ready)
+}
+
+func TestPreparingPage(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, templates.ExecuteTemplate(buf, "preparing", map[string]string{"Next": "/?tag=go"}))
+	require.Contains(t, buf.String(), "/api/v1/library/status")
+	require.Contains(t, buf.String(), `window.location.replace("/?tag=go")`)
+}